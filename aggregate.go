@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"cmp"
+	"io"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/errwrap"
+)
+
+// Bucket 拥有相同调用堆栈的 goroutine 集合
+type Bucket struct {
+	State     string  `json:"state"`
+	Frames    []Frame `json:"frames"`              // 第一个 goroutine 的调用堆栈
+	CreatedBy *Frame  `json:"createdBy,omitempty"` // 第一个 goroutine 的创建位置
+
+	IDs     []int         `json:"ids"` // 所有 goroutine 的 ID，按从小到大排列。
+	MinWait time.Duration `json:"minWait,omitempty"`
+	MaxWait time.Duration `json:"maxWait,omitempty"`
+	Locked  bool          `json:"locked,omitempty"` // 是否有 goroutine 被锁定在系统线程上
+}
+
+// Count 该集合中 goroutine 的数量
+func (b *Bucket) Count() int { return len(b.IDs) }
+
+// Goroutines 获取当前所有 goroutine 的堆栈信息
+//
+// 通过 [runtime.Stack] 获取所有 goroutine 的堆栈并由 [ParseGoroutines] 进行解析。
+func Goroutines() ([]*Goroutine, error) {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return ParseGoroutines(bytes.NewReader(buf[:n]))
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// Aggregate 将拥有相同调用堆栈的 goroutine 进行归类
+//
+// 状态、每一帧的函数名、文件和行号以及创建位置都相同的 goroutine 会被归为一类，不考虑参数和等待时间。
+// 返回值按 goroutine 的数量从多到少排列，数量相同的按最长等待时间从长到短排列。
+func Aggregate(gs []*Goroutine) []*Bucket {
+	buckets := make([]*Bucket, 0, 10)
+	indexes := make(map[string]*Bucket, 10)
+
+	for _, g := range gs {
+		sig := goroutineSignature(g)
+		b, found := indexes[sig]
+		if !found {
+			b = &Bucket{State: g.State, Frames: g.Frames, CreatedBy: g.CreatedBy, MinWait: g.Wait, MaxWait: g.Wait}
+			indexes[sig] = b
+			buckets = append(buckets, b)
+		}
+
+		b.IDs = append(b.IDs, g.ID)
+		b.MinWait = min(b.MinWait, g.Wait)
+		b.MaxWait = max(b.MaxWait, g.Wait)
+		b.Locked = b.Locked || g.Locked
+	}
+
+	for _, b := range buckets {
+		slices.Sort(b.IDs)
+	}
+
+	slices.SortStableFunc(buckets, func(a, b *Bucket) int {
+		if c := cmp.Compare(b.Count(), a.Count()); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.MaxWait, a.MaxWait); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.IDs[0], b.IDs[0])
+	})
+
+	return buckets
+}
+
+func goroutineSignature(g *Goroutine) string {
+	buf := &strings.Builder{}
+	buf.WriteString(g.State)
+	write := func(f *Frame) {
+		buf.WriteByte('\n')
+		buf.WriteString(f.Function)
+		buf.WriteByte(' ')
+		buf.WriteString(f.File)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(f.Line))
+	}
+
+	for _, f := range g.Frames {
+		write(&f)
+	}
+	if g.CreatedBy != nil {
+		buf.WriteString("\ncreated by")
+		write(g.CreatedBy)
+	}
+	return buf.String()
+}
+
+// DumpBuckets 输出 buckets 的摘要信息
+//
+// 每一类 goroutine 的第一行为数量、状态以及等待时间，之后每一帧一行，包含包名、文件名、行号以及函数名，
+// 格式如下：
+//
+//	12: chan receive [1~5 minutes] [locked to thread]
+//	    worker pool.go:88  (*Pool[...]).run
+//	    created by worker pool.go:30  New[...]
+func DumpBuckets(w io.Writer, buckets []*Bucket) error {
+	buf := errwrap.Writer{Writer: w}
+
+	for i, b := range buckets {
+		if i > 0 {
+			buf.WByte('\n')
+		}
+
+		buf.WString(strconv.Itoa(b.Count())).WString(": ").WString(b.State)
+		if b.MaxWait > 0 {
+			buf.WString(" [")
+			if b.MinWait != b.MaxWait {
+				buf.WString(strconv.Itoa(int(b.MinWait.Minutes()))).WByte('~')
+			}
+			buf.WString(strconv.Itoa(int(b.MaxWait.Minutes()))).WString(" minutes]")
+		}
+		if b.Locked {
+			buf.WString(" [locked to thread]")
+		}
+		buf.WByte('\n')
+
+		rows := make([][2]string, 0, len(b.Frames)+1)
+		for _, f := range b.Frames {
+			rows = append(rows, bucketRow(&f))
+		}
+		if b.CreatedBy != nil {
+			r := bucketRow(b.CreatedBy)
+			r[0] = "created by " + r[0]
+			rows = append(rows, r)
+		}
+
+		var width int
+		for _, r := range rows {
+			width = max(width, len(r[0]))
+		}
+		for _, r := range rows {
+			buf.WString("    ").WString(r[0]).WString(strings.Repeat(" ", width-len(r[0])+2)).WString(r[1]).WByte('\n')
+		}
+	}
+
+	return buf.Err
+}
+
+// 返回摘要信息中的一行，分别为位置和函数名。
+func bucketRow(f *Frame) [2]string {
+	loc := path.Base(trimVendor(f.Package)) + " " + filepath.Base(filepath.FromSlash(f.File)) + ":" + strconv.Itoa(f.Line)
+	return [2]string{loc, f.FuncName().Short()}
+}
+
+// DumpGoroutines 输出当前所有 goroutine 归类后的摘要信息
+//
+// 相当于依次调用 [Goroutines]、[Aggregate] 和 [DumpBuckets]，可用于程序的自我诊断。
+func DumpGoroutines(w io.Writer) error {
+	gs, err := Goroutines()
+	if err != nil {
+		return err
+	}
+	return DumpBuckets(w, Aggregate(gs))
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestAggregate(t *testing.T) {
+	a := assert.New(t, false)
+
+	f, err := os.Open("./testdata/goroutines/dup.txt")
+	a.NotError(err)
+	defer f.Close()
+	gs, err := ParseGoroutines(f)
+	a.NotError(err).Length(gs, 6)
+
+	buckets := Aggregate(gs)
+	a.Length(buckets, 4)
+
+	b := buckets[0]
+	a.Equal(b.Count(), 3).
+		Equal(b.IDs, []int{4, 5, 6}).
+		Equal(b.State, "chan receive").
+		Equal(b.MinWait, time.Minute).
+		Equal(b.MaxWait, 9*time.Minute).
+		True(b.Locked).
+		Equal(b.CreatedBy.Function, "example.com/app/worker.New")
+
+	a.Equal(buckets[1].IDs, []int{8}) // 等待时间最长
+	a.Equal(buckets[2].IDs, []int{1})
+	a.Equal(buckets[3].IDs, []int{7}) // 行号不同
+
+	buf := &bytes.Buffer{}
+	a.NotError(DumpBuckets(buf, buckets[:2]))
+	a.Equal(buf.String(), `3: chan receive [1~9 minutes] [locked to thread]
+    worker pool.go:88             (*Pool).run
+    created by worker pool.go:30  New
+
+1: select [20 minutes]
+    http transport.go:2444             (*persistConn).writeLoop
+    created by http transport.go:1777  (*Transport).dialConn
+`)
+
+	a.Empty(Aggregate(nil))
+}
+
+func TestGoroutines(t *testing.T) {
+	a := assert.New(t, false)
+
+	done := make(chan struct{})
+	defer close(done)
+	wg := &sync.WaitGroup{}
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			wg.Done()
+			<-done
+		}()
+	}
+	wg.Wait()
+
+	gs, err := Goroutines()
+	a.NotError(err).True(len(gs) >= 6)
+	a.Equal(gs[0].Frames[0].Function, "github.com/issue9/source.Goroutines")
+
+	// 状态可能是 runnable 或是 chan receive，不一定在同一个集合中。
+	var count int
+	for _, b := range Aggregate(gs) {
+		if b.CreatedBy != nil && b.CreatedBy.Function == "github.com/issue9/source.TestGoroutines" {
+			count += b.Count()
+		}
+	}
+	a.Equal(count, 5)
+
+	buf := &bytes.Buffer{}
+	a.NotError(DumpGoroutines(buf))
+	a.Contains(buf.String(), "TestGoroutines.func1\n")
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"runtime"
+	"strings"
+)
+
+// Frame 调用堆栈中的一帧信息
+type Frame struct {
+	File     string `json:"file"`     // 文件的完整路径
+	Line     int    `json:"line"`     // 行号
+	Function string `json:"function"` // 完整的函数名，比如 github.com/issue9/source.(*T).Method.func1
+
+	// 以下字段由 [ParseFuncName] 解析 Function 而来
+
+	Name     string `json:"name"`               // 函数名，不包含包名、接收者、类型参数和闭包，比如 Method
+	Package  string `json:"package,omitempty"`  // 包的导入路径，比如 github.com/issue9/source
+	Receiver string `json:"receiver,omitempty"` // 接收者的类型，比如 *T，非方法则为空。
+	Closure  int    `json:"closure,omitempty"`  // 闭包的嵌套层数，非闭包则为 0。
+
+	// 以下字段仅在从调用堆栈中获取或是由 [ParseGoroutines] 解析时才有值
+
+	Entry   uintptr `json:"entry,omitempty"`   // 相对于函数入口的偏移量
+	Inlined bool    `json:"inlined,omitempty"` // 是否为内联函数
+
+	// 参数列表的原始内容，仅在由 [ParseGoroutines] 解析时才有值。
+	Args string `json:"args,omitempty"`
+}
+
+// FuncName 返回解析后的函数名
+func (f Frame) FuncName() FuncName { return ParseFuncName(f.Function) }
+
+// Caller 返回调用者的信息
+//
+// skip 需要忽略的调用层数：
+//
+//   - 0 表示调用 Caller 的函数；
+//   - 1 表示调用 Caller 的函数的调用者，以此类推；
+//
+// 可以在封装的函数中通过调整 skip 获取真正的调用者信息。如果 skip 超出了调用堆栈的范围，返回空值。
+func Caller(skip int) Frame {
+	pc, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return Frame{}
+	}
+
+	var function string
+	if f := runtime.FuncForPC(pc); f != nil {
+		function = f.Name()
+	}
+	return newFrame(function, file, line)
+}
+
+func newFrame(function, file string, line int) Frame {
+	fn := ParseFuncName(function)
+	return Frame{
+		File:     file,
+		Line:     line,
+		Function: function,
+		Name:     fn.Func,
+		Package:  fn.Package,
+		Receiver: fn.Receiver,
+		Closure:  len(fn.Closures),
+	}
+}
+
+// 是否为包的 init 函数，编译器会将其命名为 init.0、init.1 等。
+func isInitName(names []string) bool {
+	return len(names) == 2 && names[0] == "init" && isDigits(names[1])
+}
+
+// name 是否为编译器为闭包生成的名称，比如 func1 或是嵌套闭包中的 1
+func isClosureName(name string) bool {
+	for _, prefix := range []string{"func", "gowrap", "deferwrap"} {
+		if n, found := strings.CutPrefix(name, prefix); found && n != "" && isDigits(n) {
+			return true
+		}
+	}
+	return isDigits(name)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+type callerT struct{}
+
+func (callerT) value() Frame { return Caller(0) }
+
+func (*callerT) pointer() Frame {
+	return func() Frame { return Caller(0) }()
+}
+
+// 模拟封装的函数
+func callerHelper() Frame { return Caller(1) }
+
+func TestCaller(t *testing.T) {
+	a := assert.New(t, false)
+
+	f := Caller(0)
+	a.Equal(filepath.Base(f.File), "caller_test.go").
+		Equal(f.Line, 28).
+		Equal(f.Function, "github.com/issue9/source.TestCaller").
+		Equal(f.Name, "TestCaller").
+		Equal(f.Package, "github.com/issue9/source").
+		Empty(f.Receiver).
+		Zero(f.Closure)
+
+	f = callerHelper()
+	a.Equal(f.Line, 37).Equal(f.Name, "TestCaller")
+
+	f = callerT{}.value()
+	a.Equal(f.Name, "value").Equal(f.Receiver, "callerT").Zero(f.Closure)
+
+	f = (&callerT{}).pointer()
+	a.Equal(f.Name, "pointer").Equal(f.Receiver, "*callerT").Equal(f.Closure, 1)
+
+	func() {
+		func() {
+			f = Caller(0)
+		}()
+	}()
+	a.Equal(f.Name, "TestCaller").Equal(f.Closure, 2)
+
+	a.Zero(Caller(1000))
+}
+
+func TestNewFrame(t *testing.T) {
+	a := assert.New(t, false)
+
+	f := newFrame("main.main", "", 0)
+	a.Equal(f.Package, "main").Equal(f.Name, "main")
+
+	f = newFrame("github.com/a/b.init.0", "", 0)
+	a.Equal(f.Package, "github.com/a/b").Equal(f.Name, "init.0").Zero(f.Closure)
+
+	f = newFrame("github.com/a/b.init.0.func1", "", 0)
+	a.Equal(f.Package, "github.com/a/b").Equal(f.Name, "init.0").Equal(f.Closure, 1)
+
+	f = newFrame("net/http.(*Server).Serve.gowrap3", "", 0)
+	a.Equal(f.Package, "net/http").Equal(f.Receiver, "*Server").Equal(f.Name, "Serve").Equal(f.Closure, 1)
+
+	f = newFrame("", "", 0)
+	a.Empty(f.Package).Empty(f.Name)
+}
@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/module"
+)
+
+// Kind 帧的分类
+type Kind int8
+
+// 帧的分类
+const (
+	KindApp        Kind = iota // 主模块中的代码
+	KindDependency             // 依赖项中的代码
+	KindStd                    // 标准库中的代码，不包括 runtime。
+	KindRuntime                // runtime 及其子包中的代码
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindApp:
+		return "app"
+	case KindDependency:
+		return "dependency"
+	case KindStd:
+		return "std"
+	case KindRuntime:
+		return "runtime"
+	default:
+		return "unknown"
+	}
+}
+
+// 主模块 go.mod 中的 require 指令，已应用 replace。
+var mainRequires = sync.OnceValue(func() []module.Version {
+	_, dir := mainModule()
+	if dir == "" {
+		return nil
+	}
+
+	_, mod, err := ModFile(dir)
+	if err != nil {
+		return nil
+	}
+
+	mods := make([]module.Version, 0, len(mod.Require))
+	for _, r := range mod.Require {
+		m := r.Mod
+		for _, rep := range mod.Replace {
+			if rep.Old.Path == m.Path && (rep.Old.Version == "" || rep.Old.Version == m.Version) && rep.New.Version != "" {
+				m.Version = rep.New.Version
+			}
+		}
+		mods = append(mods, m)
+	}
+	return mods
+})
+
+// Classify 对帧进行分类
+//
+// 如果是依赖项，mod 为其所在的模块及版本；如果是主模块，mod 仅包含模块路径；其它情况下 mod 为空。
+//
+// 判断依据依次为：
+//   - 文件位于 GOROOT 之下或是包路径的第一个元素中不包含 . 的为标准库；
+//   - 文件位于模块缓存中的为依赖项，模块和版本从路径中获取；
+//   - 包路径属于主模块的为主模块，main 包也被视为主模块；
+//   - 包路径属于 [debug.ReadBuildInfo] 或是主模块 go.mod 中的依赖项的为依赖项，适用于源码不存在的情况；
+//   - 以上都不符合的，视为主模块；
+//
+// 主模块的判定方式与 [ShortenPath] 相同。
+func (f Frame) Classify() (kind Kind, mod module.Version) {
+	if isStdFrame(&f) {
+		if hasPathPrefix(f.Package, "runtime") || hasPathPrefix(f.Package, "internal/runtime") {
+			return KindRuntime, mod
+		}
+		return KindStd, mod
+	}
+
+	// 模块缓存
+	var rel string
+	if IsTrimmed(f.File) {
+		rel = filepath.ToSlash(f.File)
+	} else if r, ok := relPath(pkgSource, filepath.FromSlash(f.File)); ok && !strings.HasPrefix(r, "cache/") {
+		rel = unescapeModPath(r)
+	}
+	if at := strings.IndexByte(rel, '@'); at > 0 {
+		mod.Path, mod.Version = rel[:at], rel[at+1:]
+		if slash := strings.IndexByte(mod.Version, '/'); slash >= 0 {
+			mod.Version = mod.Version[:slash]
+		}
+		return KindDependency, mod
+	}
+
+	pkg := trimVendor(f.Package)
+	modPath, _ := mainModule()
+	if pkg == "main" || (modPath != "" && (hasPathPrefix(pkg, modPath) || hasPathPrefix(strings.TrimSuffix(pkg, "_test"), modPath))) {
+		return KindApp, module.Version{Path: modPath}
+	}
+
+	if info, ok := buildInfo(); ok {
+		for _, d := range info.Deps {
+			if hasPathPrefix(pkg, d.Path) && len(d.Path) > len(mod.Path) {
+				mod = module.Version{Path: d.Path, Version: d.Version}
+				if d.Replace != nil && d.Replace.Version != "" {
+					mod.Version = d.Replace.Version
+				}
+			}
+		}
+	}
+	for _, m := range mainRequires() {
+		if hasPathPrefix(pkg, m.Path) && len(m.Path) > len(mod.Path) {
+			mod = m
+		}
+	}
+	if mod.Path != "" {
+		return KindDependency, mod
+	}
+
+	return KindApp, module.Version{Path: modPath}
+}
+
+// KindFilter 过滤分类为 kind 的帧
+//
+// 分类方式参考 [Frame.Classify]。
+func KindFilter(kind ...Kind) Filter {
+	return func(f *Frame) bool {
+		k, _ := f.Classify()
+		for _, kk := range kind {
+			if k == kk {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"golang.org/x/mod/module"
+)
+
+func TestFrame_Classify(t *testing.T) {
+	a := assert.New(t, false)
+
+	data := []struct {
+		frame Frame
+		kind  Kind
+		mod   module.Version
+	}{
+		{
+			frame: Caller(0),
+			kind:  KindApp,
+			mod:   module.Version{Path: "github.com/issue9/source"},
+		},
+		{
+			frame: newFrame("github.com/issue9/source/codegen.Dump", "github.com/issue9/source/codegen/codegen.go", 1),
+			kind:  KindApp,
+			mod:   module.Version{Path: "github.com/issue9/source"},
+		},
+		{
+			frame: newFrame("github.com/issue9/source_test.TestX", "/x/x_test.go", 1),
+			kind:  KindApp,
+			mod:   module.Version{Path: "github.com/issue9/source"},
+		},
+		{
+			frame: newFrame("main.main", "/x/main.go", 1),
+			kind:  KindApp,
+			mod:   module.Version{Path: "github.com/issue9/source"},
+		},
+		{
+			frame: newFrame("runtime.goexit", filepath.Join(stdSource, "runtime", "asm_amd64.s"), 1),
+			kind:  KindRuntime,
+		},
+		{
+			frame: newFrame("internal/runtime/maps.(*Map).Get", "internal/runtime/maps/map.go", 1),
+			kind:  KindRuntime,
+		},
+		{
+			frame: newFrame("encoding/json.Marshal", filepath.Join(stdSource, "encoding", "json", "encode.go"), 1),
+			kind:  KindStd,
+		},
+		{
+			frame: newFrame("github.com/BurntSushi/toml.Decode", filepath.Join(pkgSource, "github.com", "!burnt!sushi", "toml@v1.0.0", "decode.go"), 1),
+			kind:  KindDependency,
+			mod:   module.Version{Path: "github.com/BurntSushi/toml", Version: "v1.0.0"},
+		},
+		{
+			frame: newFrame("github.com/issue9/assert/v4.New", "github.com/issue9/assert/v4@v4.3.1/assert.go", 1),
+			kind:  KindDependency,
+			mod:   module.Version{Path: "github.com/issue9/assert/v4", Version: "v4.3.1"},
+		},
+		{ // 构建信息
+			frame: newFrame("github.com/issue9/errwrap.(*Writer).WString", "/build/errwrap/writer.go", 1),
+			kind:  KindDependency,
+			mod:   module.Version{Path: "github.com/issue9/errwrap", Version: "v0.3.3"},
+		},
+		{
+			frame: newFrame("example.com/unknown.F", "/x/unknown.go", 1),
+			kind:  KindApp,
+			mod:   module.Version{Path: "github.com/issue9/source"},
+		},
+	}
+
+	for _, item := range data {
+		kind, mod := item.frame.Classify()
+		a.Equal(kind, item.kind, item.frame.Function).
+			Equal(mod, item.mod, item.frame.Function)
+	}
+
+	a.Equal(KindApp.String(), "app").
+		Equal(KindDependency.String(), "dependency").
+		Equal(KindStd.String(), "std").
+		Equal(KindRuntime.String(), "runtime").
+		Equal(Kind(100).String(), "unknown")
+}
+
+func TestKindFilter(t *testing.T) {
+	a := assert.New(t, false)
+
+	f := KindFilter(KindStd, KindRuntime)
+	a.True(f(&Frame{Package: "runtime", File: "runtime/proc.go"})).
+		True(f(&Frame{Package: "fmt", File: "fmt/print.go"})).
+		False(f(&Frame{Package: "github.com/issue9/source", File: "github.com/issue9/source/stack.go"}))
+}
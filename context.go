@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"container/list"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SourceLine 源码中的一行
+type SourceLine struct {
+	Line    int    `json:"line"`              // 行号，从 1 开始。
+	Text    string `json:"text"`              // 该行的内容，不包含换行符。
+	Current bool   `json:"current,omitempty"` // 是否为帧所在的行
+}
+
+// 缓存的源码文件数量上限
+const sourceCacheSize = 64
+
+type sourceEntry struct {
+	file  string
+	lines []string
+}
+
+// 最近读取的源码文件，按使用时间从近到远排列，只缓存读取成功的文件。
+var sourceCache = struct {
+	mu    sync.Mutex
+	list  *list.List // *sourceEntry
+	files map[string]*list.Element
+}{list: list.New(), files: make(map[string]*list.Element, sourceCacheSize)}
+
+// Context 返回帧所在行及其前后各 n 行的源码
+//
+// 源码文件由 [SourceFile] 确定，所以 -trimpath 编译的程序也可以找到其源码。
+// 最近读取的 64 个文件的内容会被缓存，读取失败的文件不会被缓存。
+// 如果源码文件不存在，返回 [*SourceError]；如果行号超出了文件的范围，返回空值。
+func (f Frame) Context(n int) ([]SourceLine, error) {
+	lines, err := sourceLines(f.File)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Line <= 0 || f.Line > len(lines) {
+		return nil, nil
+	}
+
+	start := max(f.Line-n, 1)
+	end := min(f.Line+n, len(lines))
+	ctx := make([]SourceLine, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		ctx = append(ctx, SourceLine{Line: i, Text: lines[i-1], Current: i == f.Line})
+	}
+	return ctx, nil
+}
+
+func sourceLines(file string) ([]string, error) {
+	sourceCache.mu.Lock()
+	if elem, found := sourceCache.files[file]; found {
+		sourceCache.list.MoveToFront(elem)
+		sourceCache.mu.Unlock()
+		return elem.Value.(*sourceEntry).lines, nil
+	}
+	sourceCache.mu.Unlock()
+
+	p, err := SourceFile(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	lines := strings.Split(string(data), "\n")
+
+	sourceCache.mu.Lock()
+	defer sourceCache.mu.Unlock()
+	if elem, found := sourceCache.files[file]; found { // 其它 goroutine 已经读取
+		sourceCache.list.MoveToFront(elem)
+		return elem.Value.(*sourceEntry).lines, nil
+	}
+	sourceCache.files[file] = sourceCache.list.PushFront(&sourceEntry{file: file, lines: lines})
+	if sourceCache.list.Len() > sourceCacheSize {
+		last := sourceCache.list.Remove(sourceCache.list.Back()).(*sourceEntry)
+		delete(sourceCache.files, last.file)
+	}
+	return lines, nil
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestFrame_Context(t *testing.T) {
+	a := assert.New(t, false)
+
+	f := Caller(0) // 行号为 21
+	lines, err := f.Context(1)
+	a.NotError(err).Equal(lines, []SourceLine{
+		{Line: 20, Text: ""},
+		{Line: 21, Text: "\tf := Caller(0) // 行号为 21", Current: true},
+		{Line: 22, Text: "\tlines, err := f.Context(1)"},
+	})
+
+	// 文件的开头
+	lines, err = Frame{File: f.File, Line: 1}.Context(2)
+	a.NotError(err).Length(lines, 3).Equal(lines[0].Line, 1).True(lines[0].Current)
+
+	lines, err = Frame{File: f.File, Line: 100000}.Context(2)
+	a.NotError(err).Empty(lines)
+
+	// -trimpath
+	lines, err = Frame{File: "github.com/issue9/source/context_test.go", Line: 21}.Context(0)
+	a.NotError(err).Length(lines, 1).Equal(lines[0].Text, "\tf := Caller(0) // 行号为 21")
+
+	lines, err = Frame{File: "github.com/issue9/source/not-exists.go", Line: 21}.Context(0)
+	a.ErrorIs(err, fs.ErrNotExist).Empty(lines)
+}
+
+func TestStackTrace_Dump_context(t *testing.T) {
+	a := assert.New(t, false)
+
+	buf := &bytes.Buffer{}
+	a.NotError(NewStackTrace(0).Dump(buf, WithContext(1), MaxDepth(1)))
+	a.Contains(buf.String(), "\t  47 | \tbuf := &bytes.Buffer{}\n").
+		Contains(buf.String(), "\t> 48 | \ta.NotError(NewStackTrace(0).Dump(buf, WithContext(1), MaxDepth(1)))\n").
+		Contains(buf.String(), "\t  49 | \ta.Contains(")
+
+	// 找不到源码
+	st := &StackTrace{}
+	st.once.Do(func() {})
+	st.frames = []Frame{{Function: "main.main", File: "/not-exists/main.go", Line: 10}}
+	buf.Reset()
+	a.NotError(st.Dump(buf, WithContext(3)))
+	a.Equal(buf.String(), "main.main\n\t/not-exists/main.go:10\n")
+}
+
+func TestSourceLines(t *testing.T) {
+	a := assert.New(t, false)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+
+	// 读取失败的不会被缓存
+	lines, err := sourceLines(file)
+	a.ErrorIs(err, fs.ErrNotExist).Empty(lines)
+	a.NotError(os.WriteFile(file, []byte("package main\r\n"), os.ModePerm))
+	lines, err = sourceLines(file)
+	a.NotError(err).Equal(lines, []string{"package main", ""})
+
+	// 超出上限时淘汰最久未使用的文件
+	for i := range sourceCacheSize {
+		f := filepath.Join(dir, strconv.Itoa(i)+".go")
+		a.NotError(os.WriteFile(f, []byte("package main"), os.ModePerm))
+		_, err = sourceLines(f)
+		a.NotError(err)
+	}
+	sourceCache.mu.Lock()
+	_, found := sourceCache.files[file]
+	a.False(found).Equal(sourceCache.list.Len(), sourceCacheSize).Length(sourceCache.files, sourceCacheSize)
+	sourceCache.mu.Unlock()
+}
@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Dependency go.mod 中 require 指令引用的模块的状态
+type Dependency struct {
+	Module   module.Version
+	Indirect bool
+
+	// 本地缓存中该模块的最新版本
+	//
+	// 弃用与撤回的状态都是以该版本的 go.mod 为准，如果本地缓存中不存在任何版本，则为空。
+	Latest string
+
+	Deprecated string // 弃用信息，如果为空表示未弃用。
+
+	Retracted bool   // Module.Version 是否已经被撤回
+	Rationale string // 撤回的原因
+}
+
+// Dependencies 返回 p 所在模块的所有依赖项的弃用和撤回状态
+//
+// 会查找 p 所在模块的 go.mod，并从本地的模块缓存 $GOPATH/pkg/mod/cache/download 中读取各个依赖项的 go.mod，
+// 整个过程不会访问网络，所以结果仅代表本地缓存中的状态。
+//
+// 与 go 命令的行为一致，弃用和撤回信息都以本地缓存的最新版本的 go.mod 为准。
+func Dependencies(p string) ([]*Dependency, error) {
+	_, mod, err := ModFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := make([]*Dependency, 0, len(mod.Require))
+	for _, r := range mod.Require {
+		dep, err := dependency(r)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+func dependency(r *modfile.Require) (*Dependency, error) {
+	dep := &Dependency{Module: r.Mod, Indirect: r.Indirect}
+
+	versions, err := cachedVersions(r.Mod.Path)
+	if err != nil {
+		return nil, err
+	}
+	if dep.Latest = latestVersion(versions); dep.Latest == "" {
+		return dep, nil
+	}
+
+	mod, err := cachedModFile(r.Mod.Path, dep.Latest)
+	if err != nil {
+		return nil, err
+	}
+
+	if mod.Module != nil {
+		dep.Deprecated = mod.Module.Deprecated
+	}
+
+	for _, r := range mod.Retract {
+		if semver.Compare(r.Low, dep.Module.Version) <= 0 && semver.Compare(dep.Module.Version, r.High) <= 0 {
+			dep.Retracted = true
+			dep.Rationale = r.Rationale
+			break
+		}
+	}
+
+	return dep, nil
+}
+
+// 模块 modPath 在 $GOPATH/pkg/mod/cache/download 中的目录
+func cacheDownloadDir(modPath string) (string, error) {
+	p, err := module.EscapePath(modPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(pkgSource, "cache", "download", p, "@v"), nil
+}
+
+// 返回本地缓存中存在 go.mod 的所有版本
+func cachedVersions(modPath string) ([]string, error) {
+	dir, err := cacheDownloadDir(modPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name, found := strings.CutSuffix(e.Name(), ".mod")
+		if !found || e.IsDir() {
+			continue
+		}
+
+		v, err := module.UnescapeVersion(name)
+		if err != nil || !semver.IsValid(v) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// 返回最新的版本
+//
+// 与 go 命令相同，正式版本优先于预发布版本（包括伪版本）。
+func latestVersion(versions []string) (latest string) {
+	var pre string
+	for _, v := range versions {
+		if semver.Prerelease(v) == "" {
+			if latest == "" || semver.Compare(v, latest) > 0 {
+				latest = v
+			}
+		} else if pre == "" || semver.Compare(v, pre) > 0 {
+			pre = v
+		}
+	}
+
+	if latest == "" {
+		return pre
+	}
+	return latest
+}
+
+// 读取本地缓存中模块 modPath 的 version 版本的 go.mod
+func cachedModFile(modPath, version string) (*modfile.File, error) {
+	dir, err := cacheDownloadDir(modPath)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, v+".mod")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return modfile.ParseLax(path, data, nil)
+}
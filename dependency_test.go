@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+// 创建一个模块缓存目录并将其设置为 pkgSource
+//
+// files 为相对于 cache/download 的文件路径及其内容。
+func newModCache(a *assert.Assertion, files map[string]string) string {
+	root := a.TB().TempDir()
+	old := pkgSource
+	pkgSource = root
+	a.TB().Cleanup(func() { pkgSource = old })
+
+	for p, content := range files {
+		p = filepath.Join(root, "cache", "download", filepath.FromSlash(p))
+		a.NotError(os.MkdirAll(filepath.Dir(p), os.ModePerm))
+		a.NotError(os.WriteFile(p, []byte(content), os.ModePerm))
+	}
+
+	return root
+}
+
+func TestDependencies(t *testing.T) {
+	a := assert.New(t, false)
+	newModCache(a, map[string]string{
+		"example.com/dep/@v/v1.0.0.mod":                             "module example.com/dep\n",
+		"example.com/dep/@v/v1.1.0.mod":                             "module example.com/dep\n",
+		"example.com/dep/@v/v1.2.0-beta.1.mod":                      "// Deprecated: 请使用 example.com/dep/v2\nmodule example.com/dep\n",
+		"example.com/dep/@v/v1.2.0.mod":                             "// Deprecated: 请使用 example.com/dep/v2\nmodule example.com/dep\n\nretract v1.0.0 // 存在安全问题\n",
+		"example.com/dep/@v/list":                                   "v1.0.0\nv1.1.0\nv1.2.0\n",
+		"example.com/!upper/@v/v0.1.0.mod":                          "module example.com/Upper\n\nretract [v0.0.1, v0.0.5]\n",
+		"example.com/pre/@v/v0.0.0-20200101000000-abcdefabcdef.mod": "module example.com/pre\n",
+	})
+
+	dir := t.TempDir()
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/app
+
+require (
+	example.com/dep v1.0.0
+	example.com/Upper v0.0.3 // indirect
+	example.com/pre v0.0.0-20200101000000-abcdefabcdef
+	example.com/not-cached v1.0.0
+)
+`), os.ModePerm))
+
+	deps, err := Dependencies(dir)
+	a.NotError(err).Length(deps, 4)
+
+	d := deps[0]
+	a.Equal(d.Module.Path, "example.com/dep").
+		False(d.Indirect).
+		Equal(d.Latest, "v1.2.0").
+		Equal(d.Deprecated, "请使用 example.com/dep/v2").
+		True(d.Retracted).
+		Equal(d.Rationale, "存在安全问题")
+
+	d = deps[1]
+	a.Equal(d.Module.Path, "example.com/Upper").
+		True(d.Indirect).
+		Equal(d.Latest, "v0.1.0").
+		Empty(d.Deprecated).
+		True(d.Retracted).
+		Empty(d.Rationale)
+
+	d = deps[2]
+	a.Equal(d.Latest, "v0.0.0-20200101000000-abcdefabcdef").
+		Empty(d.Deprecated).
+		False(d.Retracted)
+
+	d = deps[3]
+	a.Empty(d.Latest).Empty(d.Deprecated).False(d.Retracted)
+
+	_, err = Dependencies("/")
+	a.Error(err)
+}
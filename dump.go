@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/issue9/errwrap"
+)
+
+const truncatedMessage = "... more frames not captured"
+
+// Option 输出调用堆栈时的选项
+type Option func(*options)
+
+type options struct {
+	maxDepth int
+	filters  []Filter
+	collapse bool
+	context  int
+	short    bool
+	link     *string
+	terminal bool
+}
+
+// MaxDepth 最多输出的帧数
+//
+// 超出的部分不会输出，而是以一行 "... n more frames" 代替。小于等于 0 表示不限制。
+// 被过滤的帧不计算在内。
+func MaxDepth(n int) Option { return func(o *options) { o.maxDepth = n } }
+
+// WithFilter 指定过滤器
+//
+// 多次调用或是指定多个过滤器时，只要有一个过滤器返回 true，该帧就会被过滤。
+func WithFilter(f ...Filter) Option { return func(o *options) { o.filters = append(o.filters, f...) } }
+
+// Collapse 将连续被过滤的帧合并为一行 "... n frames filtered" 输出
+func Collapse() Option { return func(o *options) { o.collapse = true } }
+
+// WithShortPath 以 [ShortenPath] 的格式输出文件路径
+func WithShortPath() Option { return func(o *options) { o.short = true } }
+
+// WithContext 在每一帧之后输出其所在行及前后各 n 行的源码
+//
+// 帧所在的行会以 > 开头，源码的查找方式参考 [Frame.Context]，找不到源码的帧不输出源码。
+func WithContext(n int) Option { return func(o *options) { o.context = n } }
+
+func buildOptions(opt ...Option) *options {
+	o := &options{}
+	for _, f := range opt {
+		f(o)
+	}
+	return o
+}
+
+// Dump 以文本的形式将调用堆栈写入 w
+//
+// 默认情况下，每一帧输出两行，分别为完整的函数名以及文件路径和行号，可以通过 opt 修改输出的内容。
+func (st *StackTrace) Dump(w io.Writer, opt ...Option) error {
+	return st.dump(w, buildOptions(opt...))
+}
+
+func (st *StackTrace) dump(w io.Writer, o *options) error {
+	buf := errwrap.Writer{Writer: w}
+	filter := AnyFilter(o.filters...)
+
+	var depth, more, filtered int
+	writeFiltered := func() {
+		if o.collapse && filtered > 0 {
+			buf.WString("... ").WString(strconv.Itoa(filtered)).WString(" frames filtered\n")
+		}
+		filtered = 0
+	}
+
+	for _, frame := range st.Frames() {
+		if filter(&frame) {
+			filtered++
+			continue
+		}
+
+		if o.maxDepth > 0 && depth >= o.maxDepth {
+			more++
+			continue
+		}
+
+		writeFiltered()
+		depth++
+		writeFrame(&buf, frame, "", o)
+	}
+
+	if more > 0 {
+		buf.WString("... ").WString(strconv.Itoa(more)).WString(" more frames\n")
+	} else {
+		writeFiltered()
+	}
+	if st.truncated {
+		buf.WString(truncatedMessage).WByte('\n')
+	}
+
+	return buf.Err
+}
+
+// 输出单个帧，suffix 为附加在函数名之后的内容。
+func writeFrame(buf *errwrap.Writer, frame Frame, suffix string, o *options) {
+	file := frame.File
+	if o.short {
+		file = frame.ShortFile()
+	}
+	buf.WString(frame.FuncName().String()).WString(suffix).WByte('\n').
+		WByte('\t').WString(file).WByte(':').WString(strconv.Itoa(frame.Line)).WByte('\n')
+	if o.context > 0 {
+		writeContext(buf, frame, o.context)
+	}
+}
+
+func writeContext(buf *errwrap.Writer, frame Frame, n int) {
+	lines, err := frame.Context(n)
+	if err != nil || len(lines) == 0 {
+		return
+	}
+
+	width := len(strconv.Itoa(lines[len(lines)-1].Line))
+	for _, l := range lines {
+		buf.WByte('\t')
+		if l.Current {
+			buf.WByte('>')
+		} else {
+			buf.WByte(' ')
+		}
+		num := strconv.Itoa(l.Line)
+		buf.WString(strings.Repeat(" ", width-len(num)+1)).WString(num).WString(" | ").WString(l.Text).WByte('\n')
+	}
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+// 递归 n 层之后调用 f
+func recursive(n int, f func()) {
+	if n <= 0 {
+		f()
+		return
+	}
+	recursive(n-1, f)
+}
+
+func TestStackTrace_Dump(t *testing.T) {
+	a := assert.New(t, false)
+
+	var st *StackTrace
+	recursive(100, func() { st = NewStackTrace(0) })
+	a.False(st.Truncated()).True(len(st.Frames()) > 100)
+
+	buf := &bytes.Buffer{}
+	a.NotError(st.Dump(buf))
+	s := buf.String()
+	a.Equal(strings.Count(s, "github.com/issue9/source.recursive\n"), 101).
+		Contains(s, "runtime.goexit\n"). // 最后一帧
+		NotContains(s, "more frames")
+
+	buf.Reset()
+	a.NotError(st.Dump(buf, MaxDepth(10)))
+	s = buf.String()
+	a.Equal(strings.Count(s, "\n\t"), 10).
+		Contains(s, "... "+strconv.Itoa(len(st.Frames())-10)+" more frames\n").
+		NotContains(s, "runtime.goexit")
+
+	// NewStackTraceDepth
+
+	recursive(100, func() { st = NewStackTraceDepth(0, 50) })
+	a.True(st.Truncated()).Length(st.PCs(), 50)
+	buf.Reset()
+	a.NotError(st.Dump(buf))
+	a.True(strings.HasSuffix(buf.String(), truncatedMessage+"\n"))
+
+	recursive(10, func() { st = NewStackTraceDepth(0, 50) })
+	a.False(st.Truncated()).True(len(st.PCs()) < 50)
+}
+
+func TestDumpStack(t *testing.T) {
+	a := assert.New(t, false)
+
+	buf := &bytes.Buffer{}
+	recursive(100, func() { DumpStack(buf, 1, false, "msg") })
+	s := buf.String()
+	a.True(strings.HasPrefix(s, "msg\ngithub.com/issue9/source.DumpStack\n"), s).
+		Equal(strings.Count(s, "github.com/issue9/source.recursive\n"), 101).
+		Contains(s, "runtime.goexit\n")
+
+	buf.Reset()
+	DumpStack(buf, 1, true, "msg")
+	a.NotContains(buf.String(), "runtime.goexit")
+}
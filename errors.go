@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StackError 记录了创建位置调用堆栈的错误
+type StackError struct {
+	err   error
+	stack *StackTrace
+}
+
+// Errorf 创建带调用堆栈的错误
+//
+// 参数与 [fmt.Errorf] 相同，如果参数中被 %w 包装的错误已经包含了调用堆栈，则不会再次获取调用堆栈，
+// 而是复用该调用堆栈，以保证 %+v 依然可以输出调用堆栈。
+func Errorf(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	if st := StackOf(err); st != nil {
+		return &StackError{err: err, stack: st}
+	}
+	return &StackError{err: err, stack: NewStackTrace(1)}
+}
+
+// WithStack 为 err 添加调用堆栈
+//
+// 如果 err 为 nil，返回 nil；如果 err 或是其包装的错误（包括 [errors.Join] 合并的错误）已经包含调用堆栈，则原样返回。
+func WithStack(err error) error {
+	if err == nil || StackOf(err) != nil {
+		return err
+	}
+	return &StackError{err: err, stack: NewStackTrace(1)}
+}
+
+// StackOf 返回 err 中记录的调用堆栈
+//
+// 会通过 [errors.As] 查找第一个 [StackError]，如果不存在则返回 nil。
+func StackOf(err error) *StackTrace {
+	var se *StackError
+	if errors.As(err, &se) {
+		return se.stack
+	}
+	return nil
+}
+
+func (e *StackError) Error() string { return e.err.Error() }
+
+func (e *StackError) Unwrap() error { return e.err }
+
+// Stack 返回创建该错误时的调用堆栈
+func (e *StackError) Stack() *StackTrace { return e.stack }
+
+// Format 实现 [fmt.Formatter] 接口
+//
+// %+v 会在错误信息之后输出调用堆栈，其它的格式与错误信息本身的格式相同。
+func (e *StackError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		fmt.Fprintf(s, "%+v\n", e.err)
+		e.stack.Dump(s)
+	case verb == 'v' || verb == 's':
+		io.WriteString(s, e.Error())
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(*source.StackError=%s)", verb, e.Error())
+	}
+}
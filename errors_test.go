@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+type customError struct{ code int }
+
+func (e *customError) Error() string { return fmt.Sprintf("code %d", e.code) }
+
+func TestErrorf(t *testing.T) {
+	a := assert.New(t, false)
+
+	err := Errorf("open %s: %w", "file", fs.ErrNotExist)
+	a.ErrorIs(err, fs.ErrNotExist).
+		Equal(err.Error(), "open file: file does not exist")
+
+	st := StackOf(err)
+	a.NotNil(st).
+		Equal(st.Frames()[0].Function, "github.com/issue9/source.TestErrorf").
+		Equal(st.Frames()[0].Line, 24)
+
+	var se *StackError
+	a.True(errors.As(err, &se)).Equal(se.Stack(), st)
+
+	// 已经包含调用堆栈
+	err2 := Errorf("wrap: %w", err)
+	a.ErrorIs(err2, fs.ErrNotExist).Equal(StackOf(err2), st)
+	a.True(errors.As(err2, &se)).Equal(se.Stack(), st).Equal(err2.Error(), "wrap: open file: file does not exist")
+
+	// 多个错误
+	custom := &customError{code: 5}
+	err3 := Errorf("multi: %w, %w", custom, fs.ErrPermission)
+	a.ErrorIs(err3, fs.ErrPermission)
+	var ce *customError
+	a.True(errors.As(err3, &ce)).Equal(ce.code, 5)
+	a.NotEqual(StackOf(err3), st)
+
+	err4 := Errorf("multi: %w, %w", custom, err)
+	a.Equal(StackOf(err4), st)
+}
+
+func TestWithStack(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Nil(WithStack(nil))
+
+	err := WithStack(fs.ErrExist)
+	a.ErrorIs(err, fs.ErrExist).Equal(err.Error(), fs.ErrExist.Error())
+	st := StackOf(err)
+	a.NotNil(st).Equal(st.Frames()[0].Function, "github.com/issue9/source.TestWithStack")
+
+	a.Equal(WithStack(err), err)
+
+	joined := errors.Join(fs.ErrClosed, err)
+	a.Equal(WithStack(joined), joined)
+
+	a.Nil(StackOf(fs.ErrClosed)).Nil(StackOf(nil))
+}
+
+func TestStackError_Format(t *testing.T) {
+	a := assert.New(t, false)
+
+	err := WithStack(fs.ErrExist)
+	a.Equal(fmt.Sprintf("%v", err), "file already exists").
+		Equal(fmt.Sprintf("%s", err), "file already exists").
+		Equal(fmt.Sprintf("%q", err), `"file already exists"`).
+		Equal(fmt.Sprintf("%d", err), "%!d(*source.StackError=file already exists)")
+
+	s := fmt.Sprintf("%+v", err)
+	a.True(strings.HasPrefix(s, "file already exists\ngithub.com/issue9/source.TestStackError_Format\n"), s).
+		Contains(s, "errors_test.go:74\n")
+
+	// 包装已经包含调用堆栈的错误
+	s = fmt.Sprintf("%+v", Errorf("open config: %w", err))
+	a.True(strings.HasPrefix(s, "open config: file already exists\ngithub.com/issue9/source.TestStackError_Format\n"), s).
+		Contains(s, "errors_test.go:74\n").
+		Equal(strings.Count(s, "TestStackError_Format"), 1)
+}
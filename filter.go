@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// 当前包的导入路径
+var selfPackage = reflect.TypeFor[Frame]().PkgPath()
+
+// Filter 调用堆栈的过滤器
+//
+// 返回 true 表示该帧需要被过滤掉。
+type Filter func(*Frame) bool
+
+// PackageFilter 过滤包路径以 prefix 开头的帧
+//
+// prefix 以路径为单位进行匹配，比如 github.com/issue9/web 可以匹配 github.com/issue9/web/server，
+// 但是不能匹配 github.com/issue9/webuse。
+func PackageFilter(prefix ...string) Filter {
+	return func(f *Frame) bool {
+		for _, p := range prefix {
+			if hasPathPrefix(f.Package, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RuntimeFilter 过滤 runtime 及其子包的帧
+func RuntimeFilter() Filter {
+	return func(f *Frame) bool { return isStdFrame(f) && hasPathPrefix(f.Package, "runtime") }
+}
+
+// StdFilter 过滤标准库的帧，包括 runtime。
+func StdFilter() Filter { return isStdFrame }
+
+// TestingFilter 过滤测试框架的帧
+//
+// 包括标准库的 testing 及其子包，以及 github.com/issue9/assert 和 github.com/stretchr/testify。
+func TestingFilter() Filter {
+	f := PackageFilter("github.com/issue9/assert", "github.com/stretchr/testify")
+	return func(frame *Frame) bool {
+		return (isStdFrame(frame) && hasPathPrefix(frame.Package, "testing")) || f(frame)
+	}
+}
+
+// SelfFilter 过滤当前包（github.com/issue9/source）的帧
+//
+// 当前包的测试文件中的帧不会被过滤。
+func SelfFilter() Filter {
+	return func(f *Frame) bool {
+		return f.Package == selfPackage && !strings.HasSuffix(f.File, "_test.go")
+	}
+}
+
+// DepsFilter 过滤不属于 modDir 所在模块的帧
+//
+// 模块由 modDir 所在的 go.mod 确定，标准库的帧不会被过滤，如有需要可以与 [StdFilter] 组合使用。
+// 如果 go.mod 中没有 module 指令，返回错误。
+func DepsFilter(modDir string) (Filter, error) {
+	path, mod, err := ModFile(modDir)
+	if err != nil {
+		return nil, err
+	}
+	if mod.Module == nil {
+		return nil, fmt.Errorf("%s 中缺少 module 指令", path)
+	}
+	modPath := mod.Module.Mod.Path
+
+	return func(f *Frame) bool {
+		return f.Package != "main" && !isStdFrame(f) && !hasPathPrefix(f.Package, modPath) && !hasPathPrefix(f.Package, modPath+"_test")
+	}, nil
+}
+
+// AnyFilter 只要有一个过滤器返回 true 即过滤该帧
+func AnyFilter(filter ...Filter) Filter {
+	return func(f *Frame) bool {
+		for _, ff := range filter {
+			if ff(f) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// NotFilter 反转过滤器的结果
+func NotFilter(filter Filter) Filter { return func(f *Frame) bool { return !filter(f) } }
+
+// p 是否以 prefix 开头，且以路径为单位进行匹配
+func hasPathPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// 是否为标准库中的帧
+//
+// 如果文件是绝对路径，则根据其是否在 GOROOT 之下判断，
+// 否则根据包路径的第一个元素中是否包含 . 进行判断。
+func isStdFrame(f *Frame) bool {
+	if f.File != "" && !IsTrimmed(f.File) {
+		return inDir(stdSource, filepath.FromSlash(f.File))
+	}
+	return isStdPackage(f.Package)
+}
+
+func isStdPackage(pkg string) bool {
+	if pkg == "" || pkg == "main" {
+		return false
+	}
+	first, _, _ := strings.Cut(pkg, "/")
+	return strings.IndexByte(first, '.') < 0
+}
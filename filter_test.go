@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestFilter(t *testing.T) {
+	a := assert.New(t, false)
+
+	std := &Frame{Package: "encoding/json", File: filepath.Join(stdSource, "encoding/json/decode.go")}
+	rt := &Frame{Package: "runtime", File: filepath.Join(stdSource, "runtime/panic.go")}
+	testing := &Frame{Package: "testing", File: filepath.Join(stdSource, "testing/testing.go")}
+	assert := &Frame{Package: "github.com/issue9/assert/v4", File: "github.com/issue9/assert/v4@v4.3.1/assert.go"}
+	self := &Frame{Package: "github.com/issue9/source", File: "/src/source/stack.go"}
+	selfTest := &Frame{Package: "github.com/issue9/source", File: "/src/source/stack_test.go"}
+	user := &Frame{Package: "example.com/myruntime/runtime", File: "/home/runtime/x/runtime/a.go"}
+	main := &Frame{Package: "main", File: "/src/main.go"}
+	trimmedStd := &Frame{Package: "fmt", File: "fmt/print.go"}
+
+	f := RuntimeFilter()
+	a.True(f(rt)).False(f(std)).False(f(user))
+
+	f = StdFilter()
+	a.True(f(rt)).True(f(std)).True(f(testing)).True(f(trimmedStd)).
+		False(f(user)).False(f(main)).False(f(self))
+
+	f = TestingFilter()
+	a.True(f(testing)).True(f(assert)).False(f(std)).False(f(user))
+
+	f = SelfFilter()
+	a.True(f(self)).False(f(selfTest)).False(f(user))
+
+	f = PackageFilter("example.com/myruntime", "github.com/issue9/assert")
+	a.True(f(user)).True(f(assert)).False(f(std))
+	a.False(PackageFilter("github.com/issue9/ass")(assert))
+
+	f, err := DepsFilter("./")
+	a.NotError(err).
+		True(f(assert)).True(f(user)).
+		False(f(self)).False(f(selfTest)).False(f(std)).False(f(main)).
+		False(f(&Frame{Package: "github.com/issue9/source/codegen"})).
+		False(f(&Frame{Package: "github.com/issue9/source_test"}))
+
+	_, err = DepsFilter("/")
+	a.Error(err)
+
+	// 没有 module 指令
+	dir := t.TempDir()
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("go 1.21\n"), os.ModePerm))
+	f, err = DepsFilter(dir)
+	a.ErrorString(err, "module").Nil(f)
+
+	f = AnyFilter(StdFilter(), SelfFilter())
+	a.True(f(std)).True(f(self)).False(f(user))
+	a.False(AnyFilter()(std))
+
+	f = NotFilter(StdFilter())
+	a.False(f(std)).True(f(user))
+}
+
+func TestStackTrace_Dump_filter(t *testing.T) {
+	a := assert.New(t, false)
+
+	st := NewStackTrace(0)
+	buf := &bytes.Buffer{}
+	a.NotError(st.Dump(buf, WithFilter(StdFilter())))
+	s := buf.String()
+	a.Contains(s, "TestStackTrace_Dump_filter").
+		NotContains(s, "testing.tRunner").
+		NotContains(s, "runtime.goexit").
+		NotContains(s, "filtered")
+
+	buf.Reset()
+	a.NotError(st.Dump(buf, WithFilter(StdFilter()), Collapse()))
+	a.True(strings.HasSuffix(buf.String(), "\n... 2 frames filtered\n"), buf.String())
+
+	buf.Reset()
+	recursive(3, func() { st = NewStackTrace(0) })
+	a.NotError(st.Dump(buf, WithFilter(PackageFilter("github.com/issue9/source")), Collapse(), MaxDepth(1)))
+	a.Equal(buf.String(), "... 6 frames filtered\ntesting.tRunner\n\t"+st.Frames()[6].File+":"+strconv.Itoa(st.Frames()[6].Line)+"\n... 1 more frames\n")
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Fingerprint 计算调用堆栈的指纹
+//
+// 指纹可用于对错误进行分组，具有相同指纹的调用堆栈被认为是同一个问题。计算时：
+//   - 只使用主模块中的帧（参考 [Frame.Classify]），依赖项和标准库（包括 runtime）的帧会被忽略，
+//     如果不存在主模块的帧，则使用所有非标准库的帧，如果所有的帧都是标准库，则使用所有帧；
+//   - 忽略闭包的编号，比如 func1 和 func2 被认为是相同的；
+//   - 忽略泛型的类型参数，比如 F[go.shape.int] 和 F[...] 被认为是相同的；
+//   - 不包含文件路径，同一代码在不同的机器上编译也能得到相同的指纹；
+//
+// n 表示只使用前 n 个符合上述条件的帧，小于等于 0 表示使用所有符合条件的帧；
+// line 表示是否将行号计算在内，如果为 false，那么在修改代码导致行号变化时依然能得到相同的指纹。
+//
+// 返回值为 16 个字符的十六进制字符串。
+func Fingerprint(frames []Frame, n int, line bool) string {
+	selected := make([]*Frame, 0, len(frames))
+	for i := range frames {
+		if kind, _ := frames[i].Classify(); kind == KindApp {
+			selected = append(selected, &frames[i])
+		}
+	}
+	if len(selected) == 0 {
+		for i := range frames {
+			if !isStdFrame(&frames[i]) {
+				selected = append(selected, &frames[i])
+			}
+		}
+	}
+	if len(selected) == 0 {
+		for i := range frames {
+			selected = append(selected, &frames[i])
+		}
+	}
+	if n > 0 && len(selected) > n {
+		selected = selected[:n]
+	}
+
+	h := sha256.New()
+	for _, f := range selected {
+		h.Write([]byte(normalizeFuncName(f.FuncName())))
+		if line {
+			h.Write([]byte{':'})
+			h.Write([]byte(strconv.Itoa(f.Line)))
+		}
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Fingerprint 计算调用堆栈的指纹
+//
+// 参数及返回值与函数 [Fingerprint] 相同。
+func (st *StackTrace) Fingerprint(n int, line bool) string {
+	return Fingerprint(st.Frames(), n, line)
+}
+
+// 去掉函数名中闭包的编号和泛型的类型参数
+func normalizeFuncName(fn FuncName) string {
+	fn.Package = trimVendor(fn.Package)
+	fn.TypeParams = ""
+	if i := strings.IndexByte(fn.Receiver, '['); i > 0 {
+		fn.Receiver = fn.Receiver[:i]
+	}
+
+	closures := make([]string, 0, len(fn.Closures))
+	for _, c := range fn.Closures {
+		closures = append(closures, strings.TrimRight(c, "0123456789"))
+	}
+	fn.Closures = closures
+
+	return fn.String()
+}
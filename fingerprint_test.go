@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestFingerprint(t *testing.T) {
+	a := assert.New(t, false)
+
+	frames := []Frame{
+		newFrame("runtime.gopanic", "/usr/local/go/src/runtime/panic.go", 770),
+		newFrame("example.com/app.(*T[...]).handle.func1", "/app/a.go", 10),
+		newFrame("example.com/app.F[go.shape.int]", "/app/b.go", 20),
+		newFrame("example.com/app.main", "/app/main.go", 30),
+		newFrame("testing.tRunner", "/usr/local/go/src/testing/testing.go", 1000),
+	}
+
+	fp := Fingerprint(frames, 0, true)
+	a.Length(fp, 16)
+
+	// 闭包编号、泛型参数和文件路径
+	other := []Frame{
+		newFrame("example.com/app.(*T[go.shape.string]).handle.func2", "example.com/app/a.go", 10),
+		newFrame("example.com/app.F[...]", "example.com/app/b.go", 20),
+		newFrame("example.com/app.main", "example.com/app/main.go", 30),
+	}
+	a.Equal(Fingerprint(other, 0, true), fp)
+
+	// 行号
+	other[0].Line = 11
+	a.NotEqual(Fingerprint(other, 0, true), fp).
+		Equal(Fingerprint(other, 0, false), Fingerprint(frames, 0, false))
+
+	// 前 n 帧
+	other[2] = newFrame("example.com/app.other", "/app/main.go", 30)
+	a.NotEqual(Fingerprint(other, 0, false), Fingerprint(frames, 0, false)).
+		Equal(Fingerprint(other, 2, false), Fingerprint(frames, 2, false))
+
+	// 依赖项的帧不占用前 n 帧
+	withDeps := []Frame{
+		newFrame("github.com/issue9/assert/v4.(*Assertion).Equal", "github.com/issue9/assert/v4@v4.3.1/assertion.go", 50),
+		frames[1],
+		newFrame("github.com/issue9/assert/v4.(*Assertion).TB", "github.com/issue9/assert/v4@v4.3.1/assertion.go", 60),
+		frames[2],
+		frames[3],
+	}
+	a.Equal(Fingerprint(withDeps, 2, true), Fingerprint(frames, 2, true)).
+		Equal(Fingerprint(withDeps, 0, true), fp)
+
+	// 只有依赖项和标准库
+	deps := []Frame{withDeps[0], frames[0], withDeps[2]}
+	a.Equal(Fingerprint(deps, 0, true), Fingerprint([]Frame{withDeps[0], withDeps[2]}, 0, true)).
+		NotEqual(Fingerprint(deps, 1, true), Fingerprint(deps, 2, true))
+
+	// 全是标准库
+	std := frames[:1]
+	a.NotEqual(Fingerprint(std, 0, true), Fingerprint(nil, 0, true))
+
+	// StackTrace
+	var st1, st2 *StackTrace
+	for i := range 2 {
+		st := NewStackTrace(0)
+		if i == 0 {
+			st1 = st
+		} else {
+			st2 = st
+		}
+	}
+	a.Equal(st1.Fingerprint(1, true), st2.Fingerprint(1, true))
+	st2 = NewStackTrace(0)
+	a.NotEqual(st1.Fingerprint(1, true), st2.Fingerprint(1, true)).
+		Equal(st1.Fingerprint(1, false), st2.Fingerprint(1, false))
+}
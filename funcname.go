@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"net/url"
+	"strings"
+)
+
+// FuncName 解析后的函数名
+//
+// 由 [runtime.Func.Name] 或是 [runtime.Frame.Function] 返回的函数名解析而来，
+// 比如 github.com/issue9/source.(*T[...]).Method.func1.2 会被解析为：
+//
+//	Package: github.com/issue9/source
+//	Receiver: *T[...]
+//	Func: Method
+//	Closures: [func1 2]
+type FuncName struct {
+	Package    string   // 包的导入路径，已经还原了编译器对 . 等字符的转义。
+	Receiver   string   // 接收者类型，包含 * 和类型参数，比如 *T[...]，非方法则为空。
+	Func       string   // 函数名，不包含类型参数。包级别的闭包为 glob.，包的 init 函数为 init.0 等。
+	TypeParams string   // 函数的类型参数，比如 [...]，非泛型函数为空。
+	Closures   []string // 闭包链，从外到内，比如 F.func1.2 为 [func1 2]。
+}
+
+// ParseFuncName 解析函数名
+//
+// name 为 [runtime.Func.Name] 或是 [runtime.Frame.Function] 返回的值。
+// 如果 name 中不包含包名，那么 name 整个作为 [FuncName.Func] 的值。
+func ParseFuncName(name string) FuncName {
+	var fn FuncName
+
+	// 包名中可能包含 .，比如 github.com/issue9/source，所以需要从最后一个 / 开始查找，
+	// 且最后一个 / 不能位于类型参数中，比如 F[go.shape.struct { github.com/a/b.T }]。
+	start := 0
+	depth := 0
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case '/':
+			if depth == 0 {
+				start = i + 1
+			}
+		}
+	}
+
+	dot := indexTopLevel(name[start:], '.')
+	if dot < 0 {
+		fn.Func = name
+		return fn
+	}
+	fn.Package = unescapePackage(name[:start+dot])
+
+	names := splitTopLevel(name[start+dot+1:], '.')
+
+	if len(names) > 1 && strings.HasPrefix(names[0], "(") && strings.HasSuffix(names[0], ")") { // (*T).Method
+		fn.Receiver = names[0][1 : len(names[0])-1]
+		names = names[1:]
+	} else if len(names) > 2 && names[0] == "glob" && names[1] == "" { // glob..func1
+		names = append([]string{"glob."}, names[2:]...)
+	}
+
+	// 找到第一个闭包的位置，之后的都是闭包
+	index := len(names)
+	for i := len(names) - 1; i > 0; i-- {
+		if !isClosureName(names[i]) || isInitName(names[:i+1]) {
+			break
+		}
+		index = i
+	}
+	fn.Closures = names[index:]
+	if len(fn.Closures) == 0 {
+		fn.Closures = nil
+	}
+	names = names[:index]
+
+	switch {
+	case isInitName(names):
+		fn.Func = names[0] + "." + names[1]
+	case len(names) > 1 && fn.Receiver == "": // T.Method
+		fn.Receiver = names[0]
+		fn.Func = strings.Join(names[1:], ".")
+	default:
+		fn.Func = strings.Join(names, ".")
+	}
+
+	if i := strings.IndexByte(fn.Func, '['); i > 0 {
+		fn.Func, fn.TypeParams = fn.Func[:i], fn.Func[i:]
+	}
+
+	return fn
+}
+
+// Pointer 接收者是否为指针类型
+func (fn FuncName) Pointer() bool { return strings.HasPrefix(fn.Receiver, "*") }
+
+// Short 返回不包含包名的函数名
+//
+// 比如 (*T[...]).Method.func1。
+func (fn FuncName) Short() string {
+	buf := &strings.Builder{}
+	switch {
+	case fn.Pointer():
+		buf.WriteString("(")
+		buf.WriteString(fn.Receiver)
+		buf.WriteString(").")
+	case fn.Receiver != "":
+		buf.WriteString(fn.Receiver)
+		buf.WriteString(".")
+	}
+
+	buf.WriteString(fn.Func)
+	buf.WriteString(fn.TypeParams)
+	for _, c := range fn.Closures {
+		buf.WriteString(".")
+		buf.WriteString(c)
+	}
+	return buf.String()
+}
+
+// String 返回完整的函数名
+//
+// 与 [runtime.Func.Name] 的区别在于包名中被转义的字符会被还原。
+func (fn FuncName) String() string {
+	if fn.Package == "" {
+		return fn.Short()
+	}
+	return fn.Package + "." + fn.Short()
+}
+
+// 还原编译器对包名中最后一个元素的转义，比如 github.com/a/b%2ec 还原为 github.com/a/b.c。
+func unescapePackage(pkg string) string {
+	if strings.IndexByte(pkg, '%') < 0 {
+		return pkg
+	}
+
+	if p, err := url.PathUnescape(pkg); err == nil {
+		return p
+	}
+	return pkg
+}
+
+// 查找不在 [] 和 () 中的字符 c
+func indexTopLevel(s string, c byte) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case c:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// 以不在 [] 和 () 中的字符 sep 分隔字符串
+func splitTopLevel(s string, sep byte) []string {
+	items := make([]string, 0, 4)
+	for {
+		i := indexTopLevel(s, sep)
+		if i < 0 {
+			return append(items, s)
+		}
+		items = append(items, s[:i])
+		s = s[i+1:]
+	}
+}
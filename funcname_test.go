@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+type genericT[T any] struct{}
+
+func (*genericT[T]) method() FuncName {
+	return func() FuncName { return Caller(0).FuncName() }()
+}
+
+func genericF[T any]() FuncName { return Caller(0).FuncName() }
+
+func TestParseFuncName(t *testing.T) {
+	a := assert.New(t, false)
+
+	data := []struct {
+		name  string
+		want  FuncName
+		short string
+	}{
+		{
+			name:  "main.main",
+			want:  FuncName{Package: "main", Func: "main"},
+			short: "main",
+		},
+		{
+			name:  "fmt.Println",
+			want:  FuncName{Package: "fmt", Func: "Println"},
+			short: "Println",
+		},
+		{
+			name:  "net/http.(*Server).Serve",
+			want:  FuncName{Package: "net/http", Receiver: "*Server", Func: "Serve"},
+			short: "(*Server).Serve",
+		},
+		{
+			name:  "github.com/a/b.T.Method.func1",
+			want:  FuncName{Package: "github.com/a/b", Receiver: "T", Func: "Method", Closures: []string{"func1"}},
+			short: "T.Method.func1",
+		},
+		{
+			name:  "github.com/a/b%2ec.(*T).Method.func1.2",
+			want:  FuncName{Package: "github.com/a/b.c", Receiver: "*T", Func: "Method", Closures: []string{"func1", "2"}},
+			short: "(*T).Method.func1.2",
+		},
+		{
+			name:  "github.com/a/b.F[...]",
+			want:  FuncName{Package: "github.com/a/b", Func: "F", TypeParams: "[...]"},
+			short: "F[...]",
+		},
+		{
+			name:  "github.com/a/b.F[go.shape.struct { github.com/x/y.Z }].func1",
+			want:  FuncName{Package: "github.com/a/b", Func: "F", TypeParams: "[go.shape.struct { github.com/x/y.Z }]", Closures: []string{"func1"}},
+			short: "F[go.shape.struct { github.com/x/y.Z }].func1",
+		},
+		{
+			name:  "github.com/a/b.(*T[...]).M",
+			want:  FuncName{Package: "github.com/a/b", Receiver: "*T[...]", Func: "M"},
+			short: "(*T[...]).M",
+		},
+		{
+			name:  "github.com/a/b.T[...].M.deferwrap1",
+			want:  FuncName{Package: "github.com/a/b", Receiver: "T[...]", Func: "M", Closures: []string{"deferwrap1"}},
+			short: "T[...].M.deferwrap1",
+		},
+		{
+			name:  "github.com/a/b.init.0",
+			want:  FuncName{Package: "github.com/a/b", Func: "init.0"},
+			short: "init.0",
+		},
+		{
+			name:  "github.com/a/b.init.0.func1",
+			want:  FuncName{Package: "github.com/a/b", Func: "init.0", Closures: []string{"func1"}},
+			short: "init.0.func1",
+		},
+		{
+			name:  "github.com/a/b.glob..func1",
+			want:  FuncName{Package: "github.com/a/b", Func: "glob.", Closures: []string{"func1"}},
+			short: "glob..func1",
+		},
+		{
+			name:  "github.com/a/b.(*T).M-fm",
+			want:  FuncName{Package: "github.com/a/b", Receiver: "*T", Func: "M-fm"},
+			short: "(*T).M-fm",
+		},
+		{
+			name:  "runtime.goexit",
+			want:  FuncName{Package: "runtime", Func: "goexit"},
+			short: "goexit",
+		},
+		{
+			name:  "",
+			want:  FuncName{},
+			short: "",
+		},
+	}
+
+	for _, item := range data {
+		fn := ParseFuncName(item.name)
+		a.Equal(fn, item.want, item.name).
+			Equal(fn.Short(), item.short, item.name)
+	}
+
+	fn := ParseFuncName("github.com/a/b%2ec.(*T).M")
+	a.True(fn.Pointer()).Equal(fn.String(), "github.com/a/b.c.(*T).M")
+
+	// 真实的函数名
+
+	fn = (&genericT[int]{}).method()
+	a.Equal(fn.Package, "github.com/issue9/source").
+		Equal(fn.Receiver, "*genericT[...]").
+		Equal(fn.Func, "method").
+		Equal(fn.Closures, []string{"func1"})
+
+	fn = genericF[string]()
+	a.Equal(fn.Func, "genericF").Equal(fn.TypeParams, "[...]").Empty(fn.Receiver)
+}
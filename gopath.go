@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"errors"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsGOPATHMode 文件或目录 p 是否处于 GOPATH 模式
+//
+// 满足以下任意条件即被视为 GOPATH 模式：
+//   - 环境变量 GO111MODULE 的值为 off，且 p 位于某一个 $GOPATH/src 之下；
+//   - p 位于某一个 $GOPATH/src 之下，且在 $GOPATH/src 以内找不到 go.mod；
+func IsGOPATHMode(p string) bool {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	_, ok := gopathMode(abs)
+	return ok
+}
+
+// 判断 p 是否处于 GOPATH 模式，如果是，同时返回 p 所在的 $GOPATH/src 目录。
+func gopathMode(p string) (root string, ok bool) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", false
+	}
+
+	if root = gopathRoot(abs); root == "" {
+		return "", false
+	}
+
+	if os.Getenv("GO111MODULE") == "off" {
+		return root, true
+	}
+
+	path, err := modDir(abs)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return root, true
+	case err != nil:
+		return "", false
+	}
+
+	// go.mod 位于 $GOPATH/src 之外，不能作为 p 的模块定义。
+	return root, !inDir(root, filepath.Dir(path))
+}
+
+// 返回 abs 所在的 $GOPATH/src，如果不在任何一个 GOPATH 之下，返回空值。
+func gopathRoot(abs string) string {
+	for _, root := range gopathSrc() {
+		if inDir(root, abs) {
+			return root
+		}
+	}
+	return ""
+}
+
+// 返回所有的 $GOPATH/src
+func gopathSrc() []string {
+	list := filepath.SplitList(build.Default.GOPATH)
+	roots := make([]string, 0, len(list))
+	for _, p := range list {
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			roots = append(roots, filepath.Join(abs, "src"))
+		}
+	}
+	return roots
+}
+
+// 在 GOPATH 模式下查找 pkgPath 的源码目录
+//
+// 与 go1.11 之前的 go 工具的行为相同：
+// 从 dir 开始依次向上查找各级目录下的 vendor 目录，直到 root 为止，
+// 之后再依次查找各个 $GOPATH/src 目录。
+func gopathSourceDir(pkgPath, dir, root string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if stat, err := os.Stat(abs); err == nil && !stat.IsDir() {
+		abs = filepath.Dir(abs)
+	}
+
+	pkg := filepath.FromSlash(pkgPath)
+	nf := &NotFoundError{Pkg: pkgPath}
+
+	for {
+		nf.Path = filepath.Join(abs, "vendor", pkg)
+		if isDir(nf.Path) {
+			return nf.Path, nil
+		}
+
+		if abs == root {
+			break
+		}
+		abs = filepath.Dir(abs)
+	}
+
+	for _, src := range gopathSrc() {
+		nf.Path = filepath.Join(src, pkg)
+		if isDir(nf.Path) {
+			return nf.Path, nil
+		}
+	}
+
+	return "", nf
+}
+
+// dir 是否为 parent 或是其子目录
+func inDir(parent, dir string) bool {
+	if dir == parent {
+		return true
+	}
+	return strings.HasPrefix(dir, parent+string(filepath.Separator))
+}
+
+func isDir(p string) bool {
+	stat, err := os.Stat(p)
+	return err == nil && stat.IsDir()
+}
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"go/build"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+// 创建一个 GOPATH 结构的临时目录并将其设置为 build.Default.GOPATH
+func newGOPATH(a *assert.Assertion) string {
+	root := a.TB().TempDir()
+	old := build.Default.GOPATH
+	build.Default.GOPATH = root
+	a.TB().Cleanup(func() { build.Default.GOPATH = old })
+
+	for _, dir := range []string{
+		"src/example.com/app/internal/handler",
+		"src/example.com/app/vendor/example.com/lib",
+		"src/example.com/lib",
+		"src/example.com/other",
+	} {
+		a.NotError(os.MkdirAll(filepath.Join(root, dir), os.ModePerm))
+	}
+	a.NotError(os.WriteFile(filepath.Join(root, "src/example.com/app/main.go"), []byte("package main"), os.ModePerm))
+
+	return root
+}
+
+func TestIsGOPATHMode(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.False(IsGOPATHMode("./"))
+
+	root := newGOPATH(a)
+	a.True(IsGOPATHMode(filepath.Join(root, "src/example.com/app")))
+	a.False(IsGOPATHMode("./"))
+
+	a.NotError(os.WriteFile(filepath.Join(root, "src/example.com/lib/go.mod"), []byte("module example.com/lib"), os.ModePerm))
+	a.False(IsGOPATHMode(filepath.Join(root, "src/example.com/lib")))
+	a.True(IsGOPATHMode(filepath.Join(root, "src/example.com/app")))
+}
+
+func TestPkgPath_GOPATH(t *testing.T) {
+	a := assert.New(t, false)
+	root := newGOPATH(a)
+
+	p, err := PkgPath(filepath.Join(root, "src/example.com/app"))
+	a.NotError(err).Equal(p, "example.com/app")
+
+	p, err = PkgPath(filepath.Join(root, "src/example.com/app/main.go"))
+	a.NotError(err).Equal(p, "example.com/app")
+
+	p, err = PkgPath(filepath.Join(root, "src/example.com/app/internal/handler"))
+	a.NotError(err).Equal(p, "example.com/app/internal/handler")
+
+	p, err = PkgPath(filepath.Join(root, "src"))
+	a.ErrorIs(err, fs.ErrNotExist).Empty(p)
+}
+
+func TestPkgSourceDir_GOPATH(t *testing.T) {
+	a := assert.New(t, false)
+	root := newGOPATH(a)
+	app := filepath.Join(root, "src/example.com/app/internal/handler")
+
+	// vendor
+	dir, err := PkgSourceDir("example.com/lib", app, false)
+	a.NotError(err).Equal(dir, filepath.Join(root, "src/example.com/app/vendor/example.com/lib"))
+
+	// GOPATH
+	dir, err = PkgSourceDir("example.com/other", app, false)
+	a.NotError(err).Equal(dir, filepath.Join(root, "src/example.com/other"))
+
+	dir, err = PkgSourceDir("example.com/lib", filepath.Join(root, "src/example.com/other"), false)
+	a.NotError(err).Equal(dir, filepath.Join(root, "src/example.com/lib"))
+
+	// 多个 GOPATH
+	root2 := t.TempDir()
+	a.NotError(os.MkdirAll(filepath.Join(root2, "src/example.com/second"), os.ModePerm))
+	build.Default.GOPATH = root + string(filepath.ListSeparator) + root2
+	dir, err = PkgSourceDir("example.com/second", app, false)
+	a.NotError(err).Equal(dir, filepath.Join(root2, "src/example.com/second"))
+
+	// std
+	dir, err = PkgSourceDir("encoding/json", app, false)
+	a.NotError(err).Equal(dir, filepath.Join(stdSource, "encoding/json"))
+
+	// 不包含 . 的导入路径
+	a.NotError(os.MkdirAll(filepath.Join(root, "src/myapp/models"), os.ModePerm))
+	a.NotError(os.MkdirAll(filepath.Join(root, "src/myapp/cmd/vendor/mylib"), os.ModePerm))
+	myapp := filepath.Join(root, "src/myapp/cmd")
+	dir, err = PkgSourceDir("myapp/models", myapp, false)
+	a.NotError(err).Equal(dir, filepath.Join(root, "src/myapp/models"))
+	dir, err = PkgSourceDir("mylib", myapp, false)
+	a.NotError(err).Equal(dir, filepath.Join(root, "src/myapp/cmd/vendor/mylib"))
+	dir, err = PkgSourceDir("fmt", myapp, false)
+	a.NotError(err).Equal(dir, filepath.Join(stdSource, "fmt"))
+	_, err = PkgSourceDir("myapp/not-exists", myapp, false)
+	a.ErrorIs(err, fs.ErrNotExist)
+
+	// not exists
+	dir, err = PkgSourceDir("example.com/not-exists", app, false)
+	a.ErrorIs(err, fs.ErrNotExist).Empty(dir)
+	nf, ok := err.(*NotFoundError)
+	a.True(ok).Empty(nf.ModFile).Equal(nf.Path, filepath.Join(root2, "src/example.com/not-exists"))
+}
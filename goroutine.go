@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// goroutine 1 [running]:
+// goroutine 1 gp=0xc000002380 m=0 mp=0x1234 [running]:
+var goroutineHeader = regexp.MustCompile(`^goroutine (\d+)(?: [^\[]*)? \[(.*)\]:$`)
+
+// Goroutine 从 goroutine 的堆栈信息中解析出来的数据
+type Goroutine struct {
+	ID     int           `json:"id"`
+	State  string        `json:"state"`            // 状态，比如 running、chan receive 等。
+	Wait   time.Duration `json:"wait,omitempty"`   // 等待时间，仅精确到分钟。
+	Locked bool          `json:"locked,omitempty"` // 是否被锁定在系统线程上
+	Frames []Frame       `json:"frames"`
+	Elided bool          `json:"elided,omitempty"` // 是否有被省略的帧，即包含 ...additional frames elided... 的内容。
+
+	CreatedBy *Frame `json:"createdBy,omitempty"` // 创建该 goroutine 的位置，主 goroutine 为空。
+	ParentID  int    `json:"parentID,omitempty"`  // 创建该 goroutine 的 goroutine ID，为 0 表示未知。
+
+	// 祖先 goroutine 在创建子 goroutine 时的调用堆栈
+	//
+	// 仅在指定了 GODEBUG=tracebackancestors=N 时才有内容，按从近到远的顺序排列，
+	// 即 Ancestors[0] 为创建当前 goroutine 的 goroutine。
+	Ancestors []*Ancestor `json:"ancestors,omitempty"`
+}
+
+// Ancestor 祖先 goroutine 的调用堆栈
+//
+// 由 GODEBUG=tracebackancestors=N 输出的 [originating from goroutine N]: 部分解析而来，
+// 其中的帧只有函数名、文件和行号，不包含参数。
+type Ancestor struct {
+	ID        int     `json:"id"`
+	Frames    []Frame `json:"frames"`
+	Elided    bool    `json:"elided,omitempty"`
+	CreatedBy *Frame  `json:"createdBy,omitempty"` // 创建该 goroutine 的位置，主 goroutine 为空。
+
+	// 创建该 goroutine 的 goroutine ID，为 0 表示未知。
+	//
+	// runtime 输出的祖先部分不包含 in goroutine N，此时取自下一个祖先的 ID。
+	ParentID int `json:"parentID,omitempty"`
+}
+
+// ParseGoroutines 解析 goroutine 的堆栈信息
+//
+// 支持 [runtime.Stack]、[debug.PrintStack]、SIGQUIT 以及 panic 时输出的内容，
+// r 中不属于堆栈信息的内容（比如 panic 的错误信息或是日志中的其它内容）会被忽略。
+// 解析后的 [Frame] 中，Args 为参数列表的原始内容，Entry 为 +0x 之后的偏移量。
+// 如果指定了 GODEBUG=tracebackancestors=N，祖先 goroutine 的调用堆栈会被解析到 [Goroutine.Ancestors] 中。
+func ParseGoroutines(r io.Reader) ([]*Goroutine, error) {
+	var gs []*Goroutine
+	var g *Goroutine
+	var frame *Frame   // 等待文件行的帧
+	var createdBy bool // frame 是否为 created by 行
+	var ancestor bool  // 是否处于祖先 goroutine 的部分
+
+	// 当前解析的内容需要写入的位置，在 goroutine 与其祖先之间切换。
+	var frames *[]Frame
+	var creator **Frame
+	var elided *bool
+	var parentID *int
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+
+		if m := goroutineHeader.FindStringSubmatch(line); m != nil {
+			g = &Goroutine{}
+			g.ID, _ = strconv.Atoi(m[1])
+			parseGoroutineState(g, m[2])
+			gs = append(gs, g)
+			frames, creator, elided, parentID = &g.Frames, &g.CreatedBy, &g.Elided, &g.ParentID
+			frame, ancestor = nil, false
+			continue
+		}
+
+		if g == nil {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			g, frame = nil, nil
+		case trimmed == "...additional frames elided...":
+			*elided = true
+		case strings.HasPrefix(trimmed, "[originating from goroutine ") && strings.HasSuffix(trimmed, "]:"): // GODEBUG=tracebackancestors
+			a := &Ancestor{}
+			a.ID, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(trimmed, "[originating from goroutine "), "]:"))
+			g.Ancestors = append(g.Ancestors, a)
+			frames, creator, elided, parentID = &a.Frames, &a.CreatedBy, &a.Elided, &a.ParentID
+			frame, ancestor = nil, true
+		case frame != nil && trimmed != line: // 文件行以空白字符开头
+			if !parseFileLine(frame, trimmed) {
+				g, frame = nil, nil
+				continue
+			}
+			if createdBy {
+				*creator = frame
+			} else {
+				*frames = append(*frames, *frame)
+			}
+			frame = nil
+		case strings.HasPrefix(line, "created by "):
+			name := strings.TrimPrefix(line, "created by ")
+			if index := strings.LastIndex(name, " in goroutine "); index > 0 {
+				*parentID, _ = strconv.Atoi(name[index+len(" in goroutine "):])
+				name = name[:index]
+			}
+			f := newFrame(name, "", 0)
+			frame, createdBy = &f, true
+		default:
+			name, args, ok := splitCall(line)
+			if !ok {
+				g, frame = nil, nil
+				continue
+			}
+			f := newFrame(name, "", 0)
+			if !ancestor { // 祖先 goroutine 的参数始终为 ...
+				f.Args = args
+				f.Inlined = args == "..."
+			}
+			frame, createdBy = &f, false
+		}
+	}
+
+	for _, g := range gs {
+		for i, a := range g.Ancestors {
+			if a.ParentID == 0 && a.CreatedBy != nil && i+1 < len(g.Ancestors) {
+				a.ParentID = g.Ancestors[i+1].ID
+			}
+		}
+	}
+
+	return gs, s.Err()
+}
+
+// CurrentGoroutine 获取当前 goroutine 的堆栈信息
+//
+// 包括了 goroutine 的 ID、创建位置以及祖先 goroutine 的调用堆栈（参考 [Goroutine.Ancestors]），
+// 调用堆栈从调用 CurrentGoroutine 的函数开始。
+func CurrentGoroutine() (*Goroutine, error) {
+	buf := make([]byte, 4*1024)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+
+	gs, err := ParseGoroutines(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	if len(gs) == 0 {
+		return nil, errors.New("无法解析当前 goroutine 的堆栈信息")
+	}
+
+	g := gs[0]
+	g.Frames = g.Frames[min(1, len(g.Frames)):] // CurrentGoroutine 本身
+	return g, nil
+}
+
+// 解析 goroutine 头部中 [] 之间的内容，比如 chan receive, 5 minutes, locked to thread
+func parseGoroutineState(g *Goroutine, state string) {
+	items := strings.Split(state, ", ")
+	g.State = items[0]
+	for _, item := range items[1:] {
+		switch {
+		case item == "locked to thread":
+			g.Locked = true
+		case strings.HasSuffix(item, " minutes") || strings.HasSuffix(item, " minute"):
+			n, _, _ := strings.Cut(item, " ")
+			if m, err := strconv.Atoi(n); err == nil {
+				g.Wait = time.Duration(m) * time.Minute
+			}
+		}
+	}
+}
+
+// 将 pkg.(*T).F(0x1, {0x2, 0x3}) 拆分为函数名与参数
+func splitCall(line string) (name, args string, ok bool) {
+	if !strings.HasSuffix(line, ")") {
+		return "", "", false
+	}
+
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			if depth--; depth == 0 {
+				if i == 0 {
+					return "", "", false
+				}
+				return line[:i], line[i+1 : len(line)-1], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// 解析 /path/to/file.go:12 +0x1d 形式的内容
+func parseFileLine(f *Frame, line string) bool {
+	if index := strings.LastIndex(line, " fp="); index > 0 { // GOTRACEBACK=system 时的额外信息
+		line = line[:index]
+	}
+	if index := strings.LastIndex(line, " +0x"); index > 0 {
+		if entry, err := strconv.ParseUint(line[index+len(" +0x"):], 16, 64); err == nil {
+			f.Entry = uintptr(entry)
+		}
+		line = line[:index]
+	}
+
+	index := strings.LastIndexByte(line, ':')
+	if index <= 0 {
+		return false
+	}
+	n, err := strconv.Atoi(line[index+1:])
+	if err != nil {
+		return false
+	}
+
+	f.File, f.Line = line[:index], n
+	return true
+}
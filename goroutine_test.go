@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestParseGoroutines(t *testing.T) {
+	a := assert.New(t, false)
+
+	f, err := os.Open("./testdata/goroutines/panic.txt")
+	a.NotError(err)
+	defer f.Close()
+
+	gs, err := ParseGoroutines(f)
+	a.NotError(err).Length(gs, 4)
+
+	g := gs[0]
+	a.Equal(g.ID, 1).Equal(g.State, "running").Zero(g.Wait).False(g.Locked).
+		Nil(g.CreatedBy).Zero(g.ParentID).Length(g.Frames, 3)
+	a.Equal(g.Frames[0].Function, "main.(*Server).handle").
+		Equal(g.Frames[0].Receiver, "*Server").
+		Equal(g.Frames[0].Name, "handle").
+		Equal(g.Frames[0].Args, "0xc000012345, {0xc000014000, 0x3, 0x3}").
+		Equal(g.Frames[0].File, "/home/user/app/server.go").
+		Equal(g.Frames[0].Line, 42).
+		Equal(g.Frames[0].Entry, 0x1d).
+		False(g.Frames[0].Inlined)
+	a.Equal(g.Frames[1].Function, "main.main.func1").
+		Equal(g.Frames[1].Closure, 1).
+		True(g.Frames[1].Inlined).
+		Zero(g.Frames[1].Entry).
+		Equal(g.Frames[1].Line, 12)
+	a.Equal(g.Frames[2].Args, "")
+
+	g = gs[1]
+	a.Equal(g.ID, 18).Equal(g.State, "chan receive").Equal(g.Wait, 5*time.Minute).True(g.Locked).
+		Length(g.Frames, 1).Equal(g.ParentID, 1).NotNil(g.CreatedBy)
+	a.Equal(g.Frames[0].Receiver, "*Pool[...]").
+		Equal(g.Frames[0].File, "github.com/example/worker@v1.2.3/pool.go")
+	a.Equal(g.CreatedBy.Function, "github.com/example/worker.New[...]").
+		Equal(g.CreatedBy.Line, 30).
+		Equal(g.CreatedBy.Entry, 0x99)
+
+	g = gs[2]
+	a.Equal(g.ID, 7).Equal(g.State, "select").Equal(g.Wait, time.Minute).True(g.Elided).
+		Length(g.Frames, 2).Zero(g.ParentID).
+		Equal(g.CreatedBy.Function, "net/http.(*Transport).dialConn")
+	a.Equal(g.Frames[0].File, "/usr/local/go/src/runtime/proc.go").
+		Equal(g.Frames[0].Line, 402).
+		Equal(g.Frames[0].Entry, 0xce)
+
+	g = gs[3]
+	a.Equal(g.State, "IO wait").Length(g.Frames, 1).
+		Equal(g.Frames[0].File, "C:/Users/ci/app/wait.go").
+		Equal(g.Frames[0].Line, 3)
+}
+
+func TestParseGoroutines_ancestors(t *testing.T) {
+	a := assert.New(t, false)
+
+	f, err := os.Open("./testdata/goroutines/ancestors.txt")
+	a.NotError(err)
+	defer f.Close()
+
+	gs, err := ParseGoroutines(f)
+	a.NotError(err).Length(gs, 2)
+
+	g := gs[0]
+	a.Equal(g.ID, 8).Length(g.Frames, 1).
+		Equal(g.ParentID, 7).
+		Equal(g.CreatedBy.Function, "main.middle").
+		Equal(g.CreatedBy.Line, 16).
+		Length(g.Ancestors, 2)
+
+	anc := g.Ancestors[0]
+	a.Equal(anc.ID, 7).Equal(anc.ParentID, 1).Length(anc.Frames, 1).False(anc.Elided).
+		Equal(anc.Frames[0].Function, "main.middle").
+		Equal(anc.Frames[0].Line, 17).
+		Empty(anc.Frames[0].Args).
+		False(anc.Frames[0].Inlined).
+		Equal(anc.CreatedBy.Function, "main.main").
+		Equal(anc.CreatedBy.Line, 22)
+
+	anc = g.Ancestors[1]
+	a.Equal(anc.ID, 1).Zero(anc.ParentID).Length(anc.Frames, 1).Nil(anc.CreatedBy).
+		Equal(anc.Frames[0].Function, "main.main")
+
+	g = gs[1]
+	a.Equal(g.ID, 1).Length(g.Frames, 2).Nil(g.CreatedBy).Empty(g.Ancestors).
+		Equal(g.Frames[0].Args, "0xc000012348?")
+}
+
+func TestParseGoroutines_live(t *testing.T) {
+	a := assert.New(t, false)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() { <-done }()
+
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	gs, err := ParseGoroutines(bytes.NewReader(buf))
+	a.NotError(err).True(len(gs) > 1)
+
+	g := gs[0]
+	a.Equal(g.State, "running").
+		Equal(g.Frames[0].Function, "github.com/issue9/source.TestParseGoroutines_live").
+		True(strings.HasSuffix(g.Frames[0].File, "goroutine_test.go")).
+		NotNil(g.CreatedBy)
+
+	gs, err = ParseGoroutines(strings.NewReader("panic: xx\n\nnot a goroutine\n"))
+	a.NotError(err).Empty(gs)
+}
+
+func TestCurrentGoroutine(t *testing.T) {
+	a := assert.New(t, false)
+
+	g, err := CurrentGoroutine()
+	a.NotError(err).NotNil(g).
+		True(g.ID > 0).
+		Equal(g.State, "running").
+		Equal(g.Frames[0].Function, "github.com/issue9/source.TestCurrentGoroutine").
+		NotNil(g.CreatedBy).
+		Empty(g.Ancestors)
+}
+
+func TestCurrentGoroutine_ancestors(t *testing.T) {
+	a := assert.New(t, false)
+
+	// tracebackancestors 只在启动时读取，需要在子进程中运行。
+	if os.Getenv("SOURCE_TEST_ANCESTORS") == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCurrentGoroutine_ancestors$")
+		cmd.Env = append(os.Environ(), "SOURCE_TEST_ANCESTORS=1", "GODEBUG=tracebackancestors=5")
+		out, err := cmd.CombinedOutput()
+		a.NotError(err, string(out))
+		return
+	}
+
+	g, err := CurrentGoroutine()
+	a.NotError(err)
+	parent := g.ID
+
+	ch := make(chan *Goroutine)
+	go func() {
+		go func() {
+			g, err := CurrentGoroutine()
+			a.NotError(err)
+			ch <- g
+		}()
+	}()
+	g = <-ch
+	a.NotEqual(g.ID, parent).
+		Equal(g.Frames[0].Function, "github.com/issue9/source.TestCurrentGoroutine_ancestors.func1.1").
+		Equal(g.CreatedBy.Function, "github.com/issue9/source.TestCurrentGoroutine_ancestors.func1").
+		True(len(g.Ancestors) >= 2)
+	a.Equal(g.Ancestors[0].ID, g.ParentID).
+		Equal(g.Ancestors[0].ParentID, parent).
+		Equal(g.Ancestors[0].Frames[0].Function, "github.com/issue9/source.TestCurrentGoroutine_ancestors.func1").
+		Equal(g.Ancestors[0].CreatedBy.Function, "github.com/issue9/source.TestCurrentGoroutine_ancestors").
+		Equal(g.Ancestors[1].ID, parent).
+		Equal(g.Ancestors[1].Frames[0].Function, "github.com/issue9/source.TestCurrentGoroutine_ancestors")
+}
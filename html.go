@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"embed"
+	"go/scanner"
+	"go/token"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//go:embed html
+var htmlAssets embed.FS
+
+var htmlTemplate = template.Must(template.ParseFS(htmlAssets, "html/report.html"))
+
+// HTMLReport 以 HTML 格式输出的报告
+//
+// 生成的是一个独立的页面，所有的 CSS 和 JS 都内嵌在页面之中，可以直接保存为文件或是通过 HTTP 输出。
+type HTMLReport struct {
+	Title   string
+	Message string // 错误信息，比如 panic 的内容。
+
+	Stack *StackTrace // 调用堆栈，可以为空。
+
+	// 需要输出的 goroutine，可以为空。
+	//
+	// 会通过 [Aggregate] 将拥有相同调用堆栈的 goroutine 归为一类。
+	Goroutines []*Goroutine
+
+	// 每一帧输出其所在行的前后各 Context 行的源码，为 0 表示不输出源码。
+	//
+	// 源码的查找方式参考 [Frame.Context]。
+	Context int
+}
+
+type htmlData struct {
+	Title   string
+	Message string
+	CSS     template.CSS
+	JS      template.JS
+
+	Stack  *htmlStack
+	Groups []*htmlGroup
+	Total  int
+}
+
+type htmlStack struct {
+	Frames    []*htmlFrame
+	Truncated bool
+}
+
+type htmlGroup struct {
+	Count     int
+	State     string
+	Wait      string
+	Locked    bool
+	IDs       string
+	Frames    []*htmlFrame
+	CreatedBy *htmlFrame
+}
+
+type htmlFrame struct {
+	Func     string
+	Location string
+	URL      string
+	Kind     string
+	Module   string
+	Version  string
+	Open     bool
+	Lines    []htmlLine
+}
+
+type htmlLine struct {
+	Line    int
+	Current bool
+	HTML    template.HTML
+}
+
+// Render 将报告以 HTML 格式写入 w
+//
+// 每一帧都可以折叠，主模块中的帧默认展开，其它帧默认折叠。
+// 每一帧会标记其分类（参考 [Frame.Classify]）以及所在的模块和版本，并链接到代码托管平台（参考 [Frame.URL]）。
+func (r *HTMLReport) Render(w io.Writer) error {
+	css, err := htmlAssets.ReadFile("html/report.css")
+	if err != nil {
+		return err
+	}
+	js, err := htmlAssets.ReadFile("html/report.js")
+	if err != nil {
+		return err
+	}
+
+	title := r.Title
+	if title == "" {
+		title = "stack"
+	}
+	data := &htmlData{
+		Title:   title,
+		Message: r.Message,
+		CSS:     template.CSS(css),
+		JS:      template.JS(js),
+	}
+
+	if r.Stack != nil {
+		data.Stack = &htmlStack{
+			Frames:    r.htmlFrames(r.Stack.Frames()),
+			Truncated: r.Stack.Truncated(),
+		}
+	}
+
+	for _, b := range Aggregate(r.Goroutines) {
+		g := &htmlGroup{
+			Count:  b.Count(),
+			State:  b.State,
+			Locked: b.Locked,
+			Frames: r.htmlFrames(b.Frames),
+		}
+
+		if b.MaxWait > 0 {
+			g.Wait = strconv.Itoa(int(b.MaxWait.Minutes())) + " minutes"
+			if b.MinWait != b.MaxWait {
+				g.Wait = strconv.Itoa(int(b.MinWait.Minutes())) + "~" + g.Wait
+			}
+		}
+
+		ids := make([]string, 0, len(b.IDs))
+		for _, id := range b.IDs {
+			ids = append(ids, strconv.Itoa(id))
+		}
+		g.IDs = "#" + strings.Join(ids, ", #")
+
+		if b.CreatedBy != nil {
+			g.CreatedBy = r.htmlFrame(*b.CreatedBy)
+		}
+
+		data.Groups = append(data.Groups, g)
+		data.Total += g.Count
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
+
+// ServeHTTP 将报告以 HTML 格式输出
+func (r *HTMLReport) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := r.Render(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GoroutinesHandler 以 HTML 格式输出当前所有 goroutine 的 [http.Handler]
+//
+// 每次请求都会通过 [Goroutines] 重新获取，context 表示每一帧输出的源码行数，参考 [HTMLReport.Context]。
+func GoroutinesHandler(context int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gs, err := Goroutines()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		report := &HTMLReport{Title: "goroutines", Goroutines: gs, Context: context}
+		report.ServeHTTP(w, r)
+	})
+}
+
+func (r *HTMLReport) htmlFrames(frames []Frame) []*htmlFrame {
+	hfs := make([]*htmlFrame, 0, len(frames))
+	for _, f := range frames {
+		hfs = append(hfs, r.htmlFrame(f))
+	}
+	return hfs
+}
+
+func (r *HTMLReport) htmlFrame(f Frame) *htmlFrame {
+	kind, mod := f.Classify()
+	hf := &htmlFrame{
+		Func:     f.FuncName().String(),
+		Location: f.ShortFile() + ":" + strconv.Itoa(f.Line),
+		Kind:     kind.String(),
+		Open:     kind == KindApp,
+	}
+	if kind == KindDependency {
+		hf.Module, hf.Version = mod.Path, mod.Version
+	}
+	hf.URL, _ = f.URL()
+
+	if r.Context > 0 {
+		if lines, err := f.Context(r.Context); err == nil && len(lines) > 0 {
+			texts := make([]string, 0, len(lines))
+			for _, l := range lines {
+				texts = append(texts, l.Text)
+			}
+			for i, h := range highlight(texts) {
+				hf.Lines = append(hf.Lines, htmlLine{Line: lines[i].Line, Current: lines[i].Current, HTML: h})
+			}
+		}
+	}
+
+	return hf
+}
+
+// 对 Go 源码进行语法高亮
+//
+// lines 为连续的多行源码，返回值与 lines 一一对应。
+// lines 可能截断了注释或是字符串，这种情况下只会影响高亮的结果，不会影响内容。
+func highlight(lines []string) []template.HTML {
+	src := []byte(strings.Join(lines, "\n"))
+
+	type span struct {
+		start, end int
+		class      string
+	}
+	spans := make([]span, 0, 50)
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		var class string
+		switch {
+		case tok.IsKeyword():
+			class = "kw"
+		case tok == token.STRING || tok == token.CHAR:
+			class = "str"
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			class = "num-lit"
+		case tok == token.COMMENT:
+			class = "com"
+		default:
+			continue
+		}
+
+		if lit == "" {
+			lit = tok.String()
+		}
+		start := file.Offset(pos)
+		spans = append(spans, span{start: start, end: min(start+len(lit), len(src)), class: class})
+	}
+
+	ret := make([]template.HTML, 0, len(lines))
+	offset := 0
+	for _, line := range lines {
+		start, end := offset, offset+len(line)
+		offset = end + 1
+
+		b := &strings.Builder{}
+		last := start
+		for _, sp := range spans {
+			if sp.end <= start || sp.start >= end {
+				continue
+			}
+
+			s, e := max(sp.start, start), min(sp.end, end)
+			b.WriteString(template.HTMLEscapeString(string(src[last:s])))
+			b.WriteString(`<span class="`)
+			b.WriteString(sp.class)
+			b.WriteString(`">`)
+			b.WriteString(template.HTMLEscapeString(string(src[s:e])))
+			b.WriteString("</span>")
+			last = e
+		}
+		b.WriteString(template.HTMLEscapeString(string(src[last:end])))
+		ret = append(ret, template.HTML(b.String()))
+	}
+
+	return ret
+}
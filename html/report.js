@@ -0,0 +1,6 @@
+document.querySelectorAll('[data-toggle]').forEach(function (btn) {
+    btn.addEventListener('click', function () {
+        var open = btn.dataset.toggle === 'open';
+        document.querySelectorAll('details').forEach(function (d) { d.open = open; });
+    });
+});
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestHTMLReport_Render(t *testing.T) {
+	a := assert.New(t, false)
+
+	f, err := os.Open("./testdata/goroutines/dup.txt")
+	a.NotError(err)
+	defer f.Close()
+	gs, err := ParseGoroutines(f)
+	a.NotError(err).Length(gs, 6)
+
+	st := NewStackTrace(0)
+	r := &HTMLReport{
+		Message:    "<panic>",
+		Stack:      st,
+		Goroutines: gs,
+		Context:    2,
+	}
+	buf := &bytes.Buffer{}
+	a.NotError(r.Render(buf))
+	s := buf.String()
+	a.Contains(s, "<title>stack</title>").
+		Contains(s, `<pre class="message">&lt;panic&gt;</pre>`).
+		NotContains(s, "<link").
+		NotContains(s, "<script src").
+		Contains(s, `<details class="frame app" open>`).
+		Contains(s, `<span class="func">github.com/issue9/source.TestHTMLReport_Render</span>`).
+		Contains(s, `<a href="https://github.com/issue9/source/blob/`).
+		Contains(s, `<details class="frame std">`).
+		Contains(s, `<span class="line current"><span class="num">`+strconv.Itoa(st.Frames()[0].Line)+`</span>`).
+		Contains(s, `<span class="kw">func</span>`).
+		Contains(s, `goroutines <span class="count">6</span>`).
+		Contains(s, `<span class="count">3</span> <span class="state">chan receive</span> <span class="wait">1~9 minutes</span> <span class="badge">locked to thread</span>`).
+		Contains(s, `<span class="ids">#4, #5, #6</span>`).
+		Contains(s, `<p class="note">created by</p>`).
+		Contains(s, "--runtime:") // css
+	a.NotContains(s, "more frames not captured")
+
+	// 空报告
+	buf.Reset()
+	a.NotError((&HTMLReport{Title: "empty"}).Render(buf))
+	s = buf.String()
+	a.Contains(s, "<title>empty</title>").
+		NotContains(s, `class="stack"`).
+		NotContains(s, `class="goroutines"`)
+}
+
+func TestHTMLReport_ServeHTTP(t *testing.T) {
+	a := assert.New(t, false)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	(&HTMLReport{Stack: NewStackTrace(0)}).ServeHTTP(w, r)
+	a.Equal(w.Code, http.StatusOK).
+		Equal(w.Header().Get("Content-Type"), "text/html; charset=utf-8").
+		Contains(w.Body.String(), "TestHTMLReport_ServeHTTP")
+
+	w = httptest.NewRecorder()
+	GoroutinesHandler(0).ServeHTTP(w, r)
+	a.Equal(w.Code, http.StatusOK).
+		Contains(w.Body.String(), "<title>goroutines</title>").
+		Contains(w.Body.String(), "TestHTMLReport_ServeHTTP")
+}
+
+func TestHighlight(t *testing.T) {
+	a := assert.New(t, false)
+
+	lines := highlight([]string{
+		"func f(s string) int { // <comment>",
+		"\treturn len(`a",
+		"b`) + 0x1F /* c",
+		"d */",
+	})
+	a.Equal(lines, []template.HTML{
+		`<span class="kw">func</span> f(s string) int { <span class="com">// &lt;comment&gt;</span>`,
+		"\t<span class=\"kw\">return</span> len(<span class=\"str\">`a</span>",
+		"<span class=\"str\">b`</span>) + <span class=\"num-lit\">0x1F</span> <span class=\"com\">/* c</span>",
+		`<span class="com">d */</span>`,
+	})
+
+	// 被截断的字符串
+	lines = highlight([]string{`s := "abc`})
+	a.Equal(lines, []template.HTML{`s := <span class="str">&#34;abc</span>`})
+}
@@ -9,6 +9,7 @@ import (
 	"go/build"
 	"io/fs"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"slices"
@@ -21,10 +22,43 @@ import (
 const modFile = "go.mod"
 
 var (
-	pkgSource = filepath.Join(build.Default.GOPATH, "pkg", "mod")
-	stdSource = filepath.Join(build.Default.GOROOT, "src")
+	pkgSource = modCache()
+	stdSource = filepath.Join(goroot(), "src")
 )
 
+// 返回模块缓存的目录
+//
+// 优先使用环境变量 GOMODCACHE，否则为 GOPATH 中第一个路径下的 pkg/mod。
+func modCache() string {
+	if p := os.Getenv("GOMODCACHE"); p != "" {
+		return p
+	}
+
+	gopath := build.Default.GOPATH
+	if list := filepath.SplitList(gopath); len(list) > 0 {
+		gopath = list[0]
+	}
+	return filepath.Join(gopath, "pkg", "mod")
+}
+
+// 返回 GOROOT
+//
+// 使用 -trimpath 编译时 [runtime.GOROOT] 返回空值，此时尝试从 PATH 中的 go 命令所在位置推断。
+func goroot() string {
+	if build.Default.GOROOT != "" {
+		return build.Default.GOROOT
+	}
+
+	p, err := exec.LookPath("go")
+	if err != nil {
+		return ""
+	}
+	if p, err = filepath.EvalSymlinks(p); err != nil {
+		return ""
+	}
+	return filepath.Dir(filepath.Dir(p)) // $GOROOT/bin/go
+}
+
 // PkgSourceDir 查找包 pkgPath 的源码目录
 //
 // 如果 pkgPath 是标准库的名称，如 encoding/json 等，则返回当前使用的 Go 版本对应的标准库地址。
@@ -36,15 +70,29 @@ var (
 // modDir go.mod 所在的目录，将在该文件中查找 pkgPath 指定的目录；
 // replace 是否考虑 go.mod 中的 replace 指令的影响；
 //
-// 如果找不到，会返回 [fs.ErrNotExist]
+// 如果 modDir 处于 GOPATH 模式下（参考 [IsGOPATHMode]），则会依次从 modDir 开始向上查找 vendor 目录，
+// 之后再从 GOPATH 的各个路径中查找 pkgPath，此时 replace 参数无效。
+// 不包含 . 的 pkgPath 只有在标准库中存在对应目录时才会被当作标准库。
 //
-// NOTE: 这并不会检测 dir 指向目录是否真实且准确。
+// 如果找不到，会返回 [*NotFoundError]，该错误可以通过 [errors.Is] 与 [fs.ErrNotExist] 进行匹配。
+//
+// NOTE: 除 GOPATH 模式之外，这并不会检测 dir 指向目录是否真实且准确。
 func PkgSourceDir(pkgPath, modDir string, replace bool) (dir string, err error) {
+	if root, ok := gopathMode(modDir); ok {
+		// GOPATH 模式下 myapp/models 这类不包含 . 的导入路径很常见，只有标准库中存在时才作为标准库。
+		if strings.IndexByte(pkgPath, '.') < 0 {
+			if std := filepath.Join(stdSource, pkgPath); isDir(std) {
+				return std, nil
+			}
+		}
+		return gopathSourceDir(pkgPath, modDir, root)
+	}
+
 	if strings.IndexByte(pkgPath, '.') < 0 {
 		return filepath.Join(stdSource, pkgPath), nil
 	}
 
-	_, mod, err := ModFile(modDir)
+	path, mod, err := ModFile(modDir)
 	if err != nil {
 		return "", err
 	}
@@ -52,16 +100,22 @@ func PkgSourceDir(pkgPath, modDir string, replace bool) (dir string, err error)
 	// 保证长的在前面，这样在碰到 xxx.com/pkg/v2 与 xxx.com/pkg 两个包同时出现时，v2 会出现在前面，拥有优先匹配的权利。
 	slices.SortFunc(mod.Require, func(a, b *modfile.Require) int { return len(b.Mod.Path) - len(a.Mod.Path) })
 
+	nf := &NotFoundError{Pkg: pkgPath, ModFile: path}
 	for _, pkg := range mod.Require {
-		if !strings.HasPrefix(pkgPath, pkg.Mod.Path) {
+		if strings.HasPrefix(pkg.Mod.Path, pkgPath+"/") { // 比如查找 web，但是只有 web/v2
+			nf.Candidates = append(nf.Candidates, Candidate{Module: pkg.Mod, Reason: "模块路径比包路径更长"})
 			continue
 		}
-		suffix := strings.TrimPrefix(pkgPath, pkg.Mod.Path)
-		// github.com/issue9/web 与 github.com/issue9/webuse 也匹配，但不是同一个包
-		// 只有 github.com/issue9/web 与 github.com/issue9/web/v4 这种才行
-		if suffix != "" && suffix[0] != '/' {
+
+		// github.com/issue9/web 与 github.com/issue9/webuse 前缀相同，但不是同一个包，
+		// 只有 github.com/issue9/web 与 github.com/issue9/web/v4 这种才行。
+		if !hasPathPrefix(pkgPath, pkg.Mod.Path) {
+			if similarPath(pkgPath, pkg.Mod.Path) {
+				nf.Candidates = append(nf.Candidates, Candidate{Module: pkg.Mod, Reason: "前缀相同但路径边界不匹配"})
+			}
 			continue
 		}
+		suffix := strings.TrimPrefix(pkgPath, pkg.Mod.Path)
 
 		index := slices.IndexFunc(mod.Replace, func(r *modfile.Replace) bool { return r.Old.Path == pkg.Mod.Path })
 		if !replace || index < 0 {
@@ -72,19 +126,121 @@ func PkgSourceDir(pkgPath, modDir string, replace bool) (dir string, err error)
 			return filepath.Join(pkgSource, p), nil
 		}
 
-		p := mod.Replace[index].New.Path
+		rep := mod.Replace[index]
+		p := rep.New.Path
 		if p != "" && (p[0] == '.' || p[0] == '/') { // 指向本地
 			if !filepath.IsAbs(p) {
 				p = filepath.Join(modDir, p)
 			}
 			return filepath.Abs(p)
 		}
-		return PkgSourceDir(p, modDir, false)
+
+		if rep.New.Version != "" { // 指向模块缓存中的另一个模块
+			ep, err := escapePath(p, rep.New.Version, suffix)
+			if err != nil {
+				return "", err
+			}
+			dir := filepath.Join(pkgSource, ep)
+			if isDir(dir) {
+				return dir, nil
+			}
+
+			nf.Candidates = append(nf.Candidates, Candidate{Module: pkg.Mod, Reason: "replace 之后的模块不在模块缓存中"})
+			nf.Replace = rep
+			nf.Path = dir
+			return "", nf
+		}
+
+		dir, err := PkgSourceDir(p, modDir, false)
+		if inner, ok := err.(*NotFoundError); ok {
+			nf.Candidates = append(nf.Candidates, Candidate{Module: pkg.Mod, Reason: "replace 之后的模块无法找到"})
+			nf.Candidates = append(nf.Candidates, inner.Candidates...)
+			nf.Replace = mod.Replace[index]
+			nf.Path = inner.Path
+			return "", nf
+		}
+		return dir, err
+	}
+
+	return "", nf
+}
+
+// pkgPath 与 modPath 的最后一个元素是否只是前缀相同
+//
+// 比如 github.com/issue9/web 与 github.com/issue9/webuse，以及 github.com/issue9/webuse/sub 与 github.com/issue9/web。
+func similarPath(pkgPath, modPath string) bool {
+	dir, last := path.Split(modPath)
+	rest, found := strings.CutPrefix(pkgPath, dir)
+	if !found {
+		return false
 	}
+	elem, _, _ := strings.Cut(rest, "/")
+	return elem != "" && elem != last && (strings.HasPrefix(elem, last) || strings.HasPrefix(last, elem))
+}
+
+// NotFoundError [PkgSourceDir] 找不到包时返回的错误
+//
+// 包含了查找过程中的诊断信息，可以通过 [errors.Is] 与 [fs.ErrNotExist] 进行匹配。
+type NotFoundError struct {
+	Pkg     string // 需要查找的包路径
+	ModFile string // 读取的 go.mod 文件路径，GOPATH 模式下为空。
+
+	// 尝试过但是未能匹配的模块
+	//
+	// 包括了像 github.com/issue9/web 与 github.com/issue9/webuse 这种前缀相同但不是同一模块的情况。
+	Candidates []Candidate
+
+	Replace *modfile.Replace // 应用的 replace 指令，如果未应用任何 replace 则为空。
+	Path    string           // 最后检测的路径，如果未检测任何路径则为空。
+}
 
-	return "", fs.ErrNotExist
+// Candidate 查找包时尝试过的模块
+type Candidate struct {
+	Module module.Version
+	Reason string // 未能匹配的原因
 }
 
+func (e *NotFoundError) Error() string {
+	buf := &strings.Builder{}
+	buf.WriteString("找不到包 ")
+	buf.WriteString(e.Pkg)
+
+	if e.ModFile != "" {
+		buf.WriteString("，go.mod：")
+		buf.WriteString(e.ModFile)
+	}
+
+	if len(e.Candidates) > 0 {
+		buf.WriteString("，尝试过的模块：")
+		for i, c := range e.Candidates {
+			if i > 0 {
+				buf.WriteString("、")
+			}
+			buf.WriteString(c.Module.String())
+			buf.WriteString("（")
+			buf.WriteString(c.Reason)
+			buf.WriteString("）")
+		}
+	}
+
+	if e.Replace != nil {
+		buf.WriteString("，replace：")
+		buf.WriteString(e.Replace.Old.String())
+		buf.WriteString(" => ")
+		buf.WriteString(e.Replace.New.String())
+	}
+
+	if e.Path != "" {
+		buf.WriteString("，最后检测的路径：")
+		buf.WriteString(e.Path)
+	}
+
+	return buf.String()
+}
+
+// Is 可以与 [fs.ErrNotExist] 进行匹配
+func (e *NotFoundError) Is(target error) bool { return target == fs.ErrNotExist }
+
 func escapePath(p, v, s string) (path string, err error) {
 	p, err = module.EscapePath(p)
 	if err != nil {
@@ -166,6 +322,7 @@ LOOP:
 // PkgPath 文件或目录 p 的导出路径
 //
 // 会向上查找 go.mod，根据 go.mod 中的 module 结合当前目录组成当前目录的导出路径。
+// 如果 p 处于 GOPATH 模式下（参考 [IsGOPATHMode]），则根据 p 相对于 $GOPATH/src 的路径作为导出路径。
 func PkgPath(p string) (string, error) {
 	abs, err := filepath.Abs(p)
 	if err != nil {
@@ -180,6 +337,17 @@ func PkgPath(p string) (string, error) {
 		abs = filepath.Dir(abs)
 	}
 
+	if root, ok := gopathMode(abs); ok {
+		if abs == root {
+			return "", os.ErrNotExist
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil {
+			return "", err
+		}
+		return filepath.ToSlash(rel), nil
+	}
+
 	pkgNames := make([]string, 0, 10)
 LOOP:
 	for {
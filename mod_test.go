@@ -5,7 +5,9 @@
 package source
 
 import (
+	"go/build"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -45,12 +47,88 @@ func TestPkgSourceDir(t *testing.T) {
 	dir, err = PkgSourceDir("github.com/issue9/source", "./testdata/go.mod", true)
 	a.NotError(err).FileExists(dir)
 
+	// replace 指向模块缓存中的另一个模块
+	dir = t.TempDir()
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/m
+
+require github.com/issue9/assert v1.0.0
+
+replace github.com/issue9/assert => github.com/issue9/assert/v4 v4.3.1
+`), os.ModePerm))
+	dir, err = PkgSourceDir("github.com/issue9/assert/rest", dir, true)
+	a.NotError(err).FileExists(dir).
+		True(strings.HasSuffix(filepath.ToSlash(dir), "assert/v4@v4.3.1/rest"))
+
 	// not exist
 
 	dir, err = PkgSourceDir("github.com/issue9/not-exists", "./testdata/go.mod", true)
 	a.ErrorIs(err, fs.ErrNotExist).Empty(dir)
 }
 
+func TestModCache(t *testing.T) {
+	a := assert.New(t, false)
+
+	t.Setenv("GOMODCACHE", "/cache")
+	a.Equal(modCache(), "/cache")
+
+	t.Setenv("GOMODCACHE", "")
+	old := build.Default.GOPATH
+	t.Cleanup(func() { build.Default.GOPATH = old })
+	build.Default.GOPATH = strings.Join([]string{filepath.FromSlash("/a"), filepath.FromSlash("/b")}, string(filepath.ListSeparator))
+	a.Equal(modCache(), filepath.Join(filepath.FromSlash("/a"), "pkg", "mod"))
+}
+
+func TestNotFoundError(t *testing.T) {
+	a := assert.New(t, false)
+
+	// 前缀相同但路径边界不匹配
+	dir, err := PkgSourceDir("github.com/issue9/sourcexx", "./testdata/go.mod", true)
+	a.ErrorIs(err, fs.ErrNotExist).Empty(dir)
+	nf, ok := err.(*NotFoundError)
+	a.True(ok).
+		Equal(nf.Pkg, "github.com/issue9/sourcexx").
+		True(strings.HasSuffix(filepath.ToSlash(nf.ModFile), "testdata/go.mod/go.mod")).
+		Length(nf.Candidates, 1).
+		Equal(nf.Candidates[0].Module.Path, "github.com/issue9/source").
+		Nil(nf.Replace).
+		Empty(nf.Path).
+		Contains(nf.Error(), "github.com/issue9/source@v1.0.0")
+
+	// 只有 v2
+	_, err = PkgSourceDir("github.com/issue9/web", "./testdata/go.mod", true)
+	nf, ok = err.(*NotFoundError)
+	a.True(ok).Length(nf.Candidates, 1).
+		Equal(nf.Candidates[0].Module.Path, "github.com/issue9/web/v2")
+
+	// replace 指向不存在的模块
+	_, err = PkgSourceDir("github.com/issue9/errwrap", "./testdata/go.mod", true)
+	a.ErrorIs(err, fs.ErrNotExist)
+	nf, ok = err.(*NotFoundError)
+	a.True(ok).
+		Equal(nf.Pkg, "github.com/issue9/errwrap").
+		NotNil(nf.Replace).
+		Equal(nf.Replace.New.Path, "github.com/issue9/not-exists").
+		True(strings.HasSuffix(filepath.ToSlash(nf.Path), "github.com/issue9/not-exists@v1.0.0")).
+		Contains(nf.Error(), "github.com/issue9/not-exists@v1.0.0")
+
+	// 只有 webuse
+	dir = t.TempDir()
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/m
+
+require github.com/issue9/webuse v1.0.0
+`), os.ModePerm))
+	for _, p := range []string{"github.com/issue9/web", "github.com/issue9/web/sub"} {
+		_, err = PkgSourceDir(p, dir, true)
+		nf, ok = err.(*NotFoundError)
+		a.True(ok, p).Length(nf.Candidates, 1, p).
+			Equal(nf.Candidates[0].Module.Path, "github.com/issue9/webuse", p)
+	}
+
+	// 未应用 replace
+	dir, err = PkgSourceDir("github.com/issue9/errwrap", "./testdata/go.mod", false)
+	a.NotError(err).True(strings.HasSuffix(filepath.ToSlash(dir), "errwrap@v0.3.3"))
+}
+
 func TestModFile(t *testing.T) {
 	a := assert.New(t, false)
 
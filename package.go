@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+type pkgInfo struct {
+	path, name string
+}
+
+var pkgCache sync.Map // map[uintptr]pkgInfo
+
+// CurrentPackage 获取`调用者`所在包的导入路径和包名
+//
+// 相当于部分语言的 __PACKAGE__。
+//
+// 导入路径从运行时的函数名中获取，所以被 replace 的包返回的是其原始的导入路径，
+// GOPATH 模式下 vendor 中的包会去掉 vendor 及之前的部分。main 包返回的导入路径为 main，
+// 测试中的外部测试包返回的是带 _test 后缀的路径，比如 github.com/issue9/source_test。
+//
+// 包名优先从源码文件的 package 语句中获取，如果源码不存在，则根据导入路径推断。
+//
+// 结果会根据调用位置进行缓存，相同位置的多次调用只有第一次需要解析。
+func CurrentPackage() (importPath, name string) {
+	pc, file, _, ok := runtime.Caller(1)
+	if !ok {
+		return "", ""
+	}
+
+	if info, found := pkgCache.Load(pc); found {
+		i := info.(pkgInfo)
+		return i.path, i.name
+	}
+
+	var function string
+	if f := runtime.FuncForPC(pc); f != nil {
+		function = f.Name()
+	}
+	i := pkgInfo{path: trimVendor(ParseFuncName(function).Package)}
+	i.name = packageName(file, i.path)
+	pkgCache.Store(pc, i)
+	return i.path, i.name
+}
+
+// 去掉导入路径中的 vendor 部分
+func trimVendor(p string) string {
+	if index := strings.LastIndex(p, "/vendor/"); index >= 0 {
+		return p[index+len("/vendor/"):]
+	}
+	return strings.TrimPrefix(p, "vendor/")
+}
+
+// 获取包名
+//
+// 优先读取 file 中的 package 语句，如果失败，则从导入路径 importPath 推断。
+func packageName(file, importPath string) string {
+	if file != "" {
+		if f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly); err == nil {
+			return f.Name.Name
+		}
+	}
+
+	// 以下为推断，比如：
+	//  - github.com/issue9/assert/v4 => assert
+	//  - gopkg.in/yaml.v3 => yaml
+	name := path.Base(importPath)
+	if majorDir.MatchString(name) {
+		if dir := path.Dir(importPath); dir != "." {
+			name = path.Base(dir)
+		}
+	}
+	if index := strings.Index(name, ".v"); index > 0 && isDigits(name[index+2:]) {
+		name = name[:index]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.ReplaceAll(strings.ReplaceAll(name, "-", "_"), ".", "_")
+}
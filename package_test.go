@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestCurrentPackage(t *testing.T) {
+	a := assert.New(t, false)
+
+	for range 2 { // 第二次从缓存中读取
+		p, name := CurrentPackage()
+		a.Equal(p, "github.com/issue9/source").Equal(name, "source")
+	}
+
+	func() {
+		p, name := CurrentPackage()
+		a.Equal(p, "github.com/issue9/source").Equal(name, "source")
+	}()
+}
+
+func TestTrimVendor(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(trimVendor("example.com/app/vendor/example.com/lib"), "example.com/lib").
+		Equal(trimVendor("vendor/golang.org/x/net/http2"), "golang.org/x/net/http2").
+		Equal(trimVendor("github.com/issue9/source"), "github.com/issue9/source")
+}
+
+func TestPackageName(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(packageName("./package_test.go", "example.com/x"), "source").
+		Equal(packageName("", "main"), "main").
+		Equal(packageName("", "github.com/issue9/source_test"), "source_test").
+		Equal(packageName("not-exists.go", "github.com/issue9/assert/v4"), "assert").
+		Equal(packageName("", "gopkg.in/yaml.v3"), "yaml").
+		Equal(packageName("", "github.com/x/go-sqlite"), "sqlite").
+		Equal(packageName("", "github.com/x/y-z"), "y_z")
+}
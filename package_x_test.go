@@ -0,0 +1,20 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source_test
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/source"
+)
+
+func TestCurrentPackage_external(t *testing.T) {
+	a := assert.New(t, false)
+
+	p, name := source.CurrentPackage()
+	a.Equal(p, "github.com/issue9/source_test").Equal(name, "source_test")
+}
@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/errwrap"
+)
+
+// PanicReport 捕获 panic 之后生成的报告
+type PanicReport struct {
+	Value     any              // panic 的值
+	Time      time.Time        // 捕获的时间
+	Goroutine int              // 发生 panic 的 goroutine ID，为 0 表示未知。
+	Stack     *StackTrace      // 从发生 panic 的函数开始的调用堆栈，不包含 recover 相关的帧，可以为空。
+	Build     *debug.BuildInfo // 程序的构建信息，可能为空。
+
+	// 创建该 goroutine 的位置和 goroutine ID，以及祖先 goroutine 的调用堆栈，
+	// 含义与 [Goroutine] 中的同名字段相同。
+	CreatedBy *Frame
+	ParentID  int
+	Ancestors []*Ancestor
+
+	// 输出时每一帧附带的源码行数，为 0 表示不输出源码。
+	//
+	// 源码的查找方式参考 [Frame.Context]。
+	Context int
+}
+
+// Sink 处理 [PanicReport] 的方法
+type Sink func(*PanicReport) error
+
+// NewPanicReport 根据 panic 的值 v 生成报告
+//
+// 只能在 defer 调用的函数中使用，调用堆栈从 runtime.gopanic 之后的帧开始，
+// 如果当前并未处于 panic 状态，则从调用 NewPanicReport 的函数开始。
+func NewPanicReport(v any, context int) *PanicReport {
+	r := &PanicReport{
+		Value:   v,
+		Time:    time.Now(),
+		Stack:   panicStack(1),
+		Context: context,
+	}
+	if info, ok := buildInfo(); ok {
+		r.Build = info
+	}
+	if g, err := CurrentGoroutine(); err == nil {
+		r.Goroutine, r.CreatedBy, r.ParentID, r.Ancestors = g.ID, g.CreatedBy, g.ParentID, g.Ancestors
+	}
+	return r
+}
+
+// 返回 panic 的调用堆栈
+//
+// skip 为 0 表示调用 panicStack 的函数。
+func panicStack(skip int) *StackTrace {
+	pcs, truncated := callers(skip+1, 0)
+
+	for i, pc := range pcs {
+		if f := runtime.FuncForPC(pc - 1); f == nil || f.Name() != "runtime.gopanic" {
+			continue
+		}
+
+		// 跳过 runtime.sigpanic、runtime.goPanicIndex 等由 runtime 引发 panic 的帧
+		j := i + 1
+		for ; j < len(pcs); j++ {
+			if f := runtime.FuncForPC(pcs[j] - 1); f == nil || !strings.HasPrefix(f.Name(), "runtime.") {
+				break
+			}
+		}
+		return &StackTrace{pcs: pcs[j:], truncated: truncated}
+	}
+
+	return &StackTrace{pcs: pcs, truncated: truncated}
+}
+
+// Error 返回 panic 的值
+func (r *PanicReport) Error() string { return fmt.Sprint(r.Value) }
+
+// Unwrap 如果 panic 的值是 error 类型，返回该值。
+func (r *PanicReport) Unwrap() error {
+	if err, ok := r.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Dump 以文本的形式将报告写入 w
+//
+// 格式如下：
+//
+//	panic: runtime error: index out of range [5] with length 3
+//	time: 2024-01-02T15:04:05Z
+//
+//	goroutine 18 [running]:
+//	main.f
+//		/app/main.go:12
+//	...
+//	created by main.main in goroutine 1
+//		/app/main.go:30
+//
+//	build: go1.22.0 example.com/app@v1.0.0
+//
+// 如果包含祖先 goroutine 的调用堆栈，会在 created by 之后以 [originating from goroutine N]: 的格式输出。
+func (r *PanicReport) Dump(w io.Writer) error {
+	buf := errwrap.Writer{Writer: w}
+	buf.WString("panic: ").WString(r.Error()).WByte('\n').
+		WString("time: ").WString(r.Time.Format(time.RFC3339)).WString("\n\n").
+		WString("goroutine ").WString(strconv.Itoa(r.Goroutine)).WString(" [running]:\n")
+	if buf.Err != nil {
+		return buf.Err
+	}
+
+	var opt []Option
+	if r.Context > 0 {
+		opt = append(opt, WithContext(r.Context))
+	}
+	if r.Stack != nil {
+		if err := r.Stack.Dump(w, opt...); err != nil {
+			return err
+		}
+	}
+
+	o := buildOptions(opt...)
+	if r.CreatedBy != nil {
+		var suffix string
+		if r.ParentID > 0 {
+			suffix = " in goroutine " + strconv.Itoa(r.ParentID)
+		}
+		buf.WString("created by ")
+		writeFrame(&buf, *r.CreatedBy, suffix, o)
+	}
+	for _, a := range r.Ancestors {
+		buf.WString("[originating from goroutine ").WString(strconv.Itoa(a.ID)).WString("]:\n")
+		for _, f := range a.Frames {
+			writeFrame(&buf, f, "", o)
+		}
+		if a.Elided {
+			buf.WString("...additional frames elided...\n")
+		}
+		if a.CreatedBy != nil {
+			buf.WString("created by ")
+			writeFrame(&buf, *a.CreatedBy, "", o)
+		}
+	}
+
+	if r.Build != nil {
+		buf.WString("\nbuild: ").WString(r.Build.GoVersion).WByte(' ').WString(r.Build.Main.Path)
+		if r.Build.Main.Version != "" {
+			buf.WByte('@').WString(r.Build.Main.Version)
+		}
+		buf.WByte('\n')
+	}
+	return buf.Err
+}
+
+// WriterSink 将报告以 [PanicReport.Dump] 的格式写入 w
+func WriterSink(w io.Writer) Sink { return func(r *PanicReport) error { return r.Dump(w) } }
+
+// SlogSink 将报告以 [slog.LevelError] 级别写入 l
+//
+// 调用堆栈以 stack 为键名，格式参考 [StackTrace.LogValue]。
+func SlogSink(l *slog.Logger) Sink {
+	return func(r *PanicReport) error {
+		attrs := []slog.Attr{
+			slog.Any("value", r.Value),
+			slog.Int("goroutine", r.Goroutine),
+		}
+		if r.Stack != nil {
+			attrs = append(attrs, StackAttr("stack", r.Stack))
+		}
+		if r.Build != nil {
+			attrs = append(attrs, slog.String("go", r.Build.GoVersion), slog.String("module", r.Build.Main.Path+"@"+r.Build.Main.Version))
+		}
+		l.LogAttrs(context.Background(), slog.LevelError, "panic", attrs...)
+		return nil
+	}
+}
+
+// FileSink 将每一份报告写入 dir 目录下的单独文件
+//
+// 文件名格式为 panic-20060102T150405.000000000-{goroutine}.log，内容与 [WriterSink] 相同。
+// 如果 dir 不存在，会自动创建。
+func FileSink(dir string) Sink {
+	return func(r *PanicReport) error {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+
+		name := "panic-" + r.Time.Format("20060102T150405.000000000") + "-" + strconv.Itoa(r.Goroutine) + ".log"
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+
+		return errors.Join(r.Dump(f), f.Close())
+	}
+}
+
+// Recover 捕获 panic 并将报告交由 sink 处理
+//
+// 必须以 defer source.Recover(sink, 0) 的形式调用，context 参考 [PanicReport.Context]。
+// 如果 sink 返回错误，报告和错误信息会被写入 [os.Stderr]。
+func Recover(sink Sink, context int) {
+	if v := recover(); v != nil {
+		handlePanic(NewPanicReport(v, context), sink)
+	}
+}
+
+func handlePanic(r *PanicReport, sink Sink) {
+	if err := sink(r); err != nil {
+		r.Dump(os.Stderr)
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// RecoverHandler 捕获 next 中的 panic 并将报告交由 sink 处理
+//
+// 发生 panic 时向客户端返回 500 错误，context 参考 [PanicReport.Context]。
+// [http.ErrAbortHandler] 会被重新 panic，由 [http.Server] 进行处理。
+func RecoverHandler(next http.Handler, sink Sink, context int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			handlePanic(NewPanicReport(v, context), sink)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
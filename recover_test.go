@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+// 当前 goroutine 的 ID
+func goroutineID() int {
+	g, err := CurrentGoroutine()
+	if err != nil {
+		panic(err)
+	}
+	return g.ID
+}
+
+//go:noinline
+func panicValue(v any) { panic(v) }
+
+//go:noinline
+func panicIndex(s []int, i int) int { return s[i] }
+
+//go:noinline
+func panicNil(p *int) int { return *p }
+
+func TestRecover(t *testing.T) {
+	a := assert.New(t, false)
+
+	var report *PanicReport
+	sink := func(r *PanicReport) error {
+		report = r
+		return nil
+	}
+
+	func() {
+		defer Recover(sink, 0)
+		panicValue("abc")
+	}()
+	a.NotNil(report).
+		Equal(report.Value, "abc").
+		Equal(report.Error(), "abc").
+		Nil(report.Unwrap()).
+		Equal(report.Goroutine, goroutineID()).
+		False(report.Time.IsZero()).
+		NotNil(report.Build).
+		Equal(report.Stack.Frames()[0].Function, "github.com/issue9/source.panicValue").
+		Equal(report.Stack.Frames()[1].Function, "github.com/issue9/source.TestRecover.func2")
+
+	// runtime 引发的 panic
+	report = nil
+	func() {
+		defer Recover(sink, 0)
+		panicIndex([]int{1}, 5)
+	}()
+	a.NotNil(report).
+		Equal(report.Stack.Frames()[0].Function, "github.com/issue9/source.panicIndex")
+	var re interface{ RuntimeError() }
+	a.True(errors.As(report, &re))
+
+	report = nil
+	func() {
+		defer Recover(sink, 0)
+		panicNil(nil)
+	}()
+	a.NotNil(report).
+		Equal(report.Stack.Frames()[0].Function, "github.com/issue9/source.panicNil")
+
+	// 未 panic
+	report = nil
+	func() {
+		defer Recover(sink, 0)
+	}()
+	a.Nil(report)
+}
+
+func TestNewPanicReport(t *testing.T) {
+	a := assert.New(t, false)
+
+	r := NewPanicReport("abc", 1)
+	a.Equal(r.Stack.Frames()[0].Function, "github.com/issue9/source.TestNewPanicReport").
+		Equal(r.Context, 1)
+}
+
+func TestPanicReport_Dump(t *testing.T) {
+	a := assert.New(t, false)
+
+	buf := &bytes.Buffer{}
+	func() {
+		defer Recover(WriterSink(buf), 1)
+		panicValue(errors.New("abc"))
+	}()
+	s := buf.String()
+	a.True(strings.HasPrefix(s, "panic: abc\ntime: ")).
+		Contains(s, "\n\ngoroutine "+strconv.Itoa(goroutineID())+" [running]:\ngithub.com/issue9/source.panicValue\n").
+		Contains(s, "\t> ").
+		Contains(s, "\nbuild: go").
+		NotContains(s, "source.Recover").
+		NotContains(s, "runtime.gopanic")
+
+	gs, err := ParseGoroutines(strings.NewReader(s))
+	a.NotError(err).Length(gs, 1).
+		Equal(gs[0].ID, goroutineID()).
+		Equal(gs[0].State, "running")
+}
+
+func TestPanicReport_createdBy(t *testing.T) {
+	a := assert.New(t, false)
+
+	parent := goroutineID()
+	buf := &bytes.Buffer{}
+	done := make(chan *PanicReport)
+	go func() {
+		defer Recover(func(r *PanicReport) error {
+			err := r.Dump(buf)
+			done <- r
+			return err
+		}, 0)
+		panicValue("abc")
+	}()
+	r := <-done
+	a.NotEqual(r.Goroutine, parent).
+		Equal(r.ParentID, parent).
+		Equal(r.CreatedBy.Function, "github.com/issue9/source.TestPanicReport_createdBy")
+	a.Contains(buf.String(), "\ncreated by github.com/issue9/source.TestPanicReport_createdBy in goroutine "+strconv.Itoa(parent)+"\n\t")
+}
+
+func TestPanicReport_ancestors(t *testing.T) {
+	a := assert.New(t, false)
+
+	r := &PanicReport{
+		Value:     "abc",
+		CreatedBy: &Frame{Function: "example.com/a%2eb.f", File: "/a.go", Line: 1},
+		ParentID:  1,
+		Ancestors: []*Ancestor{{ID: 1, Frames: []Frame{{Function: "example.com/a%2eb.g", File: "/a.go", Line: 2}}}},
+	}
+	buf := &bytes.Buffer{}
+	a.NotError(r.Dump(buf))
+	a.Contains(buf.String(), "\ncreated by example.com/a.b.f in goroutine 1\n\t/a.go:1\n").
+		Contains(buf.String(), "[originating from goroutine 1]:\nexample.com/a.b.g\n\t/a.go:2\n")
+}
+
+func TestPanicReport_nilStack(t *testing.T) {
+	a := assert.New(t, false)
+
+	r := &PanicReport{Value: "abc"}
+	buf := &bytes.Buffer{}
+	a.NotPanic(func() { a.NotError(WriterSink(buf)(r)) })
+	a.Equal(buf.String(), "panic: abc\ntime: 0001-01-01T00:00:00Z\n\ngoroutine 0 [running]:\n")
+
+	buf.Reset()
+	a.NotPanic(func() { a.NotError(SlogSink(slog.New(slog.NewJSONHandler(buf, nil)))(r)) })
+	a.Contains(buf.String(), `"value":"abc"`).NotContains(buf.String(), `"stack"`)
+}
+
+func TestSlogSink(t *testing.T) {
+	a := assert.New(t, false)
+
+	buf := &bytes.Buffer{}
+	func() {
+		defer Recover(SlogSink(slog.New(slog.NewJSONHandler(buf, nil))), 0)
+		panicValue("abc")
+	}()
+	s := buf.String()
+	a.Contains(s, `"level":"ERROR","msg":"panic","value":"abc","goroutine":`).
+		Contains(s, `"stack":{"0":{"function":"github.com/issue9/source.panicValue"`).
+		Contains(s, `"go":"go`)
+}
+
+func TestFileSink(t *testing.T) {
+	a := assert.New(t, false)
+
+	dir := filepath.Join(t.TempDir(), "panics")
+	func() {
+		defer Recover(FileSink(dir), 0)
+		panicValue("abc")
+	}()
+
+	entries, err := os.ReadDir(dir)
+	a.NotError(err).Length(entries, 1)
+	name := entries[0].Name()
+	a.True(strings.HasPrefix(name, "panic-")).
+		True(strings.HasSuffix(name, "-"+strconv.Itoa(goroutineID())+".log"))
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	a.NotError(err).True(bytes.HasPrefix(data, []byte("panic: abc\n")))
+}
+
+func TestRecoverHandler(t *testing.T) {
+	a := assert.New(t, false)
+
+	var report *PanicReport
+	sink := func(r *PanicReport) error {
+		report = r
+		return nil
+	}
+
+	h := RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/panic":
+			panicValue("abc")
+		case "/abort":
+			panic(http.ErrAbortHandler)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}), sink, 0)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal(w.Code, http.StatusAccepted).Nil(report)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	a.Equal(w.Code, http.StatusInternalServerError).
+		NotNil(report).
+		Equal(report.Value, "abc").
+		Equal(report.Stack.Frames()[0].Function, "github.com/issue9/source.panicValue")
+
+	report = nil
+	a.PanicValue(func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	}, http.ErrAbortHandler)
+	a.Nil(report)
+}
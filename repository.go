@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Repository 代码托管平台的链接模板
+//
+// Template 中可以使用以下占位符：
+//   - {repo} 仓库的根路径，比如 github.com/issue9/source；
+//   - {name} 仓库根路径的最后一个元素，比如 source；
+//   - {ref} 版本号对应的 tag 或是伪版本号中的提交；
+//   - {path} 文件相对于仓库根目录的路径；
+//   - {line} 行号；
+type Repository struct {
+	Prefix   string // 模块路径的前缀，比如 github.com。
+	Segments int    // 仓库的根路径包含的路径元素数量，比如 github.com/owner/repo 为 3。
+	Template string
+}
+
+// 标准库的链接模板，与 pkg.go.dev 中的源码链接相同。
+const stdTemplate = "https://cs.opensource.google/go/go/+/{ref}:src/{path};l={line}"
+
+var (
+	repositoriesMu sync.RWMutex
+	repositories   = []*Repository{
+		{Prefix: "github.com", Segments: 3, Template: "https://{repo}/blob/{ref}/{path}#L{line}"},
+		{Prefix: "gitlab.com", Segments: 3, Template: "https://{repo}/-/blob/{ref}/{path}#L{line}"},
+		{Prefix: "codeberg.org", Segments: 3, Template: "https://{repo}/src/{ref}/{path}#L{line}"},
+		{Prefix: "gitea.com", Segments: 3, Template: "https://{repo}/src/{ref}/{path}#L{line}"},
+		{Prefix: "bitbucket.org", Segments: 3, Template: "https://{repo}/src/{ref}/{path}#lines-{line}"},
+		{Prefix: "golang.org/x", Segments: 3, Template: "https://go.googlesource.com/{name}/+/{ref}/{path}#{line}"},
+	}
+)
+
+// RegisterRepository 注册代码托管平台
+//
+// 后注册的拥有更高的优先级，可以覆盖默认的 github.com 等平台。
+func RegisterRepository(r ...*Repository) {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	r = slices.Clone(r)
+	slices.Reverse(r)
+	repositories = append(r, repositories...)
+}
+
+func findRepository(modPath string) *Repository {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
+	for _, r := range repositories {
+		if hasPathPrefix(modPath, r.Prefix) {
+			return r
+		}
+	}
+	return nil
+}
+
+// URL 帧在代码托管平台上对应的链接
+//
+// 链接由模块路径、版本号以及文件在模块中的路径拼接而成，不会访问网络。
+// 标准库和 runtime 指向 Go 的源码；依赖项根据其版本号指向对应的 tag 或是提交；
+// 主模块则使用 [debug.ReadBuildInfo] 中记录的版本号或是 vcs.revision，都不存在时使用 HEAD。
+//
+// 如果无法确定链接，返回 false。可以通过 [RegisterRepository] 添加自定义的代码托管平台。
+func (f Frame) URL() (string, bool) {
+	if f.File == "" || f.Line <= 0 {
+		return "", false
+	}
+
+	kind, mod := f.Classify()
+	switch kind {
+	case KindStd, KindRuntime:
+		rel := filepath.ToSlash(f.File)
+		if !IsTrimmed(f.File) {
+			r, ok := relPath(stdSource, filepath.FromSlash(f.File))
+			if !ok {
+				return "", false
+			}
+			rel = r
+		}
+		return expandTemplate(stdTemplate, "", goRef(), rel, f.Line), true
+	case KindApp:
+		mod.Version = mainVersion()
+	}
+
+	if mod.Path == "" {
+		return "", false
+	}
+
+	rel, ok := moduleFile(f, kind, mod)
+	if !ok {
+		return "", false
+	}
+
+	r := findRepository(mod.Path)
+	if r == nil {
+		return "", false
+	}
+	elems := strings.Split(mod.Path, "/")
+	if len(elems) < r.Segments {
+		return "", false
+	}
+	repo := strings.Join(elems[:r.Segments], "/")
+
+	// 模块位于仓库的子目录中，比如 github.com/owner/repo/sub/v2，
+	// 子目录为 sub，tag 也需要加上 sub/ 前缀。
+	prefix, _, _ := module.SplitPathVersion(mod.Path)
+	subdir := strings.Trim(strings.TrimPrefix(prefix, repo), "/")
+
+	return expandTemplate(r.Template, repo, versionRef(mod.Version, subdir), path.Join(subdir, rel), f.Line), true
+}
+
+func expandTemplate(tpl, repo, ref, p string, line int) string {
+	return strings.NewReplacer(
+		"{repo}", repo,
+		"{name}", path.Base(repo),
+		"{ref}", ref,
+		"{path}", p,
+		"{line}", strconv.Itoa(line),
+	).Replace(tpl)
+}
+
+// 将版本号转换为代码托管平台可识别的引用
+//
+// version 也可以是 vcs.revision 中记录的提交，此时原样返回。
+func versionRef(version, subdir string) string {
+	if isRevision(version) {
+		return version
+	}
+	if !semver.IsValid(version) {
+		return "HEAD"
+	}
+
+	// 伪版本号中包含了提交的前 12 位
+	if module.IsPseudoVersion(version) {
+		if rev, err := module.PseudoVersionRev(version); err == nil {
+			return rev
+		}
+	}
+
+	// +incompatible 或是 +dirty 等构建信息不属于 tag
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+
+	if subdir != "" {
+		return subdir + "/" + version
+	}
+	return version
+}
+
+// 是否为 git 或 hg 的提交，即 40 位的 SHA-1 或是 64 位的 SHA-256 哈希。
+func isRevision(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// 当前 Go 版本在源码仓库中对应的引用
+func goRef() string {
+	if v := runtime.Version(); strings.HasPrefix(v, "go") && strings.IndexByte(v, ' ') < 0 {
+		return v
+	}
+	return "master" // 开发版本
+}
+
+// 主模块的版本号
+func mainVersion() string {
+	info, ok := buildInfo()
+	if !ok {
+		return "HEAD"
+	}
+
+	if v := info.Main.Version; semver.IsValid(v) {
+		return v
+	}
+
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && s.Value != "" {
+			return s.Value
+		}
+	}
+	return "HEAD"
+}
+
+// 文件相对于模块根目录的路径
+func moduleFile(f Frame, kind Kind, mod module.Version) (string, bool) {
+	file := filepath.ToSlash(f.File)
+
+	if IsTrimmed(f.File) {
+		if rel, found := strings.CutPrefix(file, mod.Path+"@"+mod.Version+"/"); found {
+			return rel, true
+		}
+		if rel, found := strings.CutPrefix(file, mod.Path+"/"); found {
+			return rel, true
+		}
+	} else if kind == KindDependency {
+		if rel, ok := relPath(pkgSource, filepath.FromSlash(f.File)); ok && !strings.HasPrefix(rel, "cache/") {
+			if _, rel, found := strings.Cut(unescapeModPath(rel), "@"); found {
+				if _, rel, found = strings.Cut(rel, "/"); found {
+					return rel, true
+				}
+			}
+		}
+	} else if _, dir := mainModule(); dir != "" {
+		if rel, ok := relPath(dir, filepath.FromSlash(f.File)); ok {
+			return rel, true
+		}
+	}
+
+	// 文件不在可识别的位置（比如 replace 指向的本地目录），根据包路径推断。
+	pkg := strings.TrimSuffix(trimVendor(f.Package), "_test")
+	if !hasPathPrefix(pkg, mod.Path) {
+		return "", false
+	}
+	return path.Join(strings.TrimPrefix(pkg[len(mod.Path):], "/"), path.Base(file)), true
+}
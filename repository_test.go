@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestFrame_URL(t *testing.T) {
+	a := assert.New(t, false)
+
+	data := []struct {
+		frame Frame
+		url   string
+	}{
+		{
+			frame: newFrame("github.com/issue9/assert/v4.New", "github.com/issue9/assert/v4@v4.3.1/assert.go", 10),
+			url:   "https://github.com/issue9/assert/blob/v4.3.1/assert.go#L10",
+		},
+		{ // 子目录中的模块
+			frame: newFrame("github.com/owner/repo/sub/v2/x.F", "github.com/owner/repo/sub/v2@v2.1.0/x/a.go", 10),
+			url:   "https://github.com/owner/repo/blob/sub/v2.1.0/sub/x/a.go#L10",
+		},
+		{ // 伪版本号
+			frame: newFrame("gitlab.com/a/b.F", "gitlab.com/a/b@v0.0.0-20240101000000-abcdef123456/c.go", 10),
+			url:   "https://gitlab.com/a/b/-/blob/abcdef123456/c.go#L10",
+		},
+		{
+			frame: newFrame("bitbucket.org/a/b.F", "bitbucket.org/a/b@v3.0.0+incompatible/c.go", 5),
+			url:   "https://bitbucket.org/a/b/src/v3.0.0/c.go#lines-5",
+		},
+		{
+			frame: newFrame("codeberg.org/a/b/c.F", "codeberg.org/a/b@v1.0.0/c/c.go", 5),
+			url:   "https://codeberg.org/a/b/src/v1.0.0/c/c.go#L5",
+		},
+		{
+			frame: newFrame("golang.org/x/mod/modfile.Parse", "golang.org/x/mod@v0.34.0/modfile/rule.go", 10),
+			url:   "https://go.googlesource.com/mod/+/v0.34.0/modfile/rule.go#10",
+		},
+		{ // 模块缓存
+			frame: newFrame("github.com/BurntSushi/toml.Decode", filepath.Join(pkgSource, "github.com", "!burnt!sushi", "toml@v1.0.0", "decode.go"), 1),
+			url:   "https://github.com/BurntSushi/toml/blob/v1.0.0/decode.go#L1",
+		},
+		{
+			frame: newFrame("fmt.Println", filepath.Join(stdSource, "fmt", "print.go"), 10),
+			url:   "https://cs.opensource.google/go/go/+/" + goRef() + ":src/fmt/print.go;l=10",
+		},
+		{
+			frame: newFrame("runtime.main", "runtime/proc.go", 10),
+			url:   "https://cs.opensource.google/go/go/+/" + goRef() + ":src/runtime/proc.go;l=10",
+		},
+		{ // 未知的平台
+			frame: newFrame("example.com/a.F", "example.com/a@v1.0.0/a.go", 10),
+		},
+		{
+			frame: Frame{Function: "F"},
+		},
+	}
+
+	for _, item := range data {
+		url, ok := item.frame.URL()
+		a.Equal(ok, item.url != "", item.frame.Function).
+			Equal(url, item.url, item.frame.Function)
+	}
+
+	// 主模块
+	f := Caller(0)
+	url, ok := f.URL()
+	a.True(ok).
+		True(strings.HasPrefix(url, "https://github.com/issue9/source/blob/"), url).
+		True(strings.HasSuffix(url, "/repository_test.go#L"+strconv.Itoa(f.Line)), url)
+}
+
+func TestRegisterRepository(t *testing.T) {
+	a := assert.New(t, false)
+
+	old := repositories
+	t.Cleanup(func() { repositories = old })
+
+	f := newFrame("git.example.com/group/repo/pkg.F", "git.example.com/group/repo@v1.2.0/pkg/f.go", 10)
+	_, ok := f.URL()
+	a.False(ok)
+
+	RegisterRepository(
+		&Repository{Prefix: "git.example.com", Segments: 3, Template: "https://{repo}/src/{ref}/{path}?line={line}&name={name}"},
+		&Repository{Prefix: "git.example.com/group", Segments: 3, Template: "https://example.com/{name}/{ref}/{path}#{line}"},
+	)
+	url, ok := f.URL()
+	a.True(ok).Equal(url, "https://example.com/repo/v1.2.0/pkg/f.go#10")
+
+	// 覆盖默认的平台
+	RegisterRepository(&Repository{Prefix: "github.com", Segments: 3, Template: "https://mirror.example.com/{repo}@{ref}/{path}"})
+	f = newFrame("github.com/issue9/assert/v4.New", "github.com/issue9/assert/v4@v4.3.1/assert.go", 10)
+	url, ok = f.URL()
+	a.True(ok).Equal(url, "https://mirror.example.com/github.com/issue9/assert@v4.3.1/assert.go")
+}
+
+func TestVersionRef(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(versionRef("", ""), "HEAD").
+		Equal(versionRef("(devel)", ""), "HEAD").
+		Equal(versionRef("v1.0.0", ""), "v1.0.0").
+		Equal(versionRef("v1.0.0", "sub"), "sub/v1.0.0").
+		Equal(versionRef("v2.0.0+incompatible", ""), "v2.0.0").
+		Equal(versionRef("v1.2.4-0.20240101000000-abcdef123456", "sub"), "abcdef123456").
+		Equal(versionRef("v0.0.0-20240101000000-abcdef123456+dirty", ""), "abcdef123456").
+		Equal(versionRef("0123456789abcdef0123456789abcdef01234567", "sub"), "0123456789abcdef0123456789abcdef01234567").
+		Equal(versionRef("0123456789abcdef0123456789abcdef0123456", ""), "HEAD"). // 39 位
+		Equal(versionRef("0123456789ABCDEF0123456789ABCDEF01234567", ""), "HEAD")
+}
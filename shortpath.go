@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+const stdPrefix = "std:"
+
+// ShortenPath 返回适合显示的文件路径
+//
+// 根据 file 所在的位置返回不同的格式：
+//   - 标准库：std:encoding/json/decode.go；
+//   - 模块缓存：github.com/issue9/assert/v4@v4.3.1/assert.go，模块路径和版本号会被还原为转义前的内容；
+//   - 主模块：相对于主模块根目录的路径，比如 codegen/codegen.go；
+//
+// 主模块是指当前工作目录所在的模块，其它情况下原样返回。
+// file 也可以是 -trimpath 处理过的路径（参考 [IsTrimmed]）。
+func ShortenPath(file string) string {
+	modPath, dir := mainModule()
+
+	if IsTrimmed(file) {
+		if modPath != "" {
+			if rel, found := strings.CutPrefix(file, modPath+"/"); found {
+				return rel
+			}
+		}
+		if first, _, _ := strings.Cut(file, "/"); strings.IndexByte(first, '.') < 0 && strings.IndexByte(file, '@') < 0 {
+			return stdPrefix + file
+		}
+		return file
+	}
+
+	p := filepath.FromSlash(file)
+
+	if rel, ok := relPath(stdSource, p); ok {
+		return stdPrefix + rel
+	}
+
+	if rel, ok := relPath(pkgSource, p); ok && !strings.HasPrefix(rel, "cache/") {
+		return unescapeModPath(rel)
+	}
+
+	if dir != "" {
+		if rel, ok := relPath(dir, p); ok {
+			return rel
+		}
+	}
+
+	return file
+}
+
+// ShortFile 返回适合显示的文件路径
+//
+// 格式参考 [ShortenPath]。
+func (f Frame) ShortFile() string { return ShortenPath(f.File) }
+
+// 返回 p 相对于 dir 的路径，如果 p 不在 dir 之中，返回 false。
+func relPath(dir, p string) (string, bool) {
+	if dir == "" || !inDir(dir, p) || dir == p {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// 还原 github.com/!burnt!sushi/toml@v1.0.0/decode.go 中被转义的模块路径和版本号
+func unescapeModPath(p string) string {
+	at := strings.IndexByte(p, '@')
+	if at < 0 {
+		return p
+	}
+
+	modPath, version := p[:at], p[at+1:]
+	var rest string
+	if slash := strings.IndexByte(version, '/'); slash >= 0 {
+		version, rest = version[:slash], version[slash:]
+	}
+
+	if mp, err := module.UnescapePath(modPath); err == nil {
+		modPath = mp
+	}
+	if v, err := module.UnescapeVersion(version); err == nil {
+		version = v
+	}
+	return modPath + "@" + version + rest
+}
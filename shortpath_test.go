@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestShortenPath(t *testing.T) {
+	a := assert.New(t, false)
+
+	abs, err := filepath.Abs("./codegen/codegen.go")
+	a.NotError(err)
+
+	// 主模块
+	a.Equal(ShortenPath(abs), "codegen/codegen.go").
+		Equal(ShortenPath("github.com/issue9/source/codegen/codegen.go"), "codegen/codegen.go")
+
+	// 标准库
+	a.Equal(ShortenPath(filepath.Join(stdSource, "encoding", "json", "decode.go")), "std:encoding/json/decode.go").
+		Equal(ShortenPath("encoding/json/decode.go"), "std:encoding/json/decode.go")
+
+	// 模块缓存
+	a.Equal(ShortenPath(filepath.Join(pkgSource, "github.com", "issue9", "assert", "v4@v4.3.1", "assert.go")), "github.com/issue9/assert/v4@v4.3.1/assert.go").
+		Equal(ShortenPath(filepath.Join(pkgSource, "github.com", "!burnt!sushi", "toml@v1.0.0", "decode.go")), "github.com/BurntSushi/toml@v1.0.0/decode.go").
+		Equal(ShortenPath("github.com/issue9/assert/v4@v4.3.1/assert.go"), "github.com/issue9/assert/v4@v4.3.1/assert.go")
+
+	// 其它
+	a.Equal(ShortenPath("/not-exists/main.go"), "/not-exists/main.go").
+		Equal(ShortenPath("example.com/x/main.go"), "example.com/x/main.go").
+		Equal(ShortenPath(filepath.Join(pkgSource, "cache", "download", "x")), filepath.Join(pkgSource, "cache", "download", "x"))
+
+	a.Equal(Frame{File: abs}.ShortFile(), "codegen/codegen.go")
+}
+
+func TestStackTrace_Dump_shortPath(t *testing.T) {
+	a := assert.New(t, false)
+
+	buf := &bytes.Buffer{}
+	a.NotError(NewStackTrace(0).Dump(buf, WithShortPath()))
+	a.Contains(buf.String(), "\tshortpath_test.go:46\n").
+		Contains(buf.String(), "\tstd:testing/testing.go:").
+		Contains(buf.String(), "\tstd:runtime/")
+}
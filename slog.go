@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strconv"
+)
+
+// LogValue 实现 [slog.LogValuer] 接口
+//
+// 返回一个分组，每一帧为以其索引为键名的子分组，包含 function、file 和 line 三个字段，
+// 其中 function 与 [StackTrace.Dump] 输出的函数名相同。st 为 nil 时返回空的分组。
+func (st *StackTrace) LogValue() slog.Value {
+	if st == nil {
+		return slog.GroupValue()
+	}
+
+	frames := st.Frames()
+	attrs := make([]slog.Attr, 0, len(frames))
+	for i, f := range frames {
+		attrs = append(attrs, slog.Group(strconv.Itoa(i),
+			slog.String("function", f.FuncName().String()),
+			slog.String("file", f.File),
+			slog.Int("line", f.Line),
+		))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// StackAttr 将调用堆栈 st 转换为 [slog.Attr]
+//
+// 值的格式参考 [StackTrace.LogValue]，st 为 nil 时值为空的分组，slog 不会输出该字段。
+func StackAttr(key string, st *StackTrace) slog.Attr {
+	return slog.Attr{Key: key, Value: st.LogValue()}
+}
+
+type slogHandler struct {
+	root slog.Handler // 未调用过 WithAttrs 和 WithGroup 的对象
+	h    slog.Handler // root 依次应用 ops 之后的对象
+	ops  []func(slog.Handler) slog.Handler
+
+	grouped bool // ops 中是否包含 WithGroup
+	level   slog.Leveler
+	source  bool
+}
+
+// NewSlogHandler 包装 h 使其支持输出调用堆栈
+//
+// level 表示日志级别大于等于该值时，会添加键名为 stack 的调用堆栈，其格式参考 [StackAttr]，
+// 调用堆栈从输出日志的位置开始，不包含 slog 本身的帧，如果为 nil，则不添加调用堆栈；
+// source 表示是否添加键名为 [slog.SourceKey] 的源码位置，与 [slog.HandlerOptions.AddSource] 不同，
+// 其中的文件路径由 [ShortenPath] 进行了处理，函数名不包含包名。使用此功能时 h 不应该再设置 AddSource。
+//
+// 与 [slog.HandlerOptions.AddSource] 相同，添加的字段始终位于顶层，不受 [slog.Handler.WithGroup] 的影响。
+// 为此在调用过 WithGroup 之后，每一条需要添加字段的日志都会在 h 上重新应用所有的 WithAttrs 和 WithGroup，
+// 之前通过 WithAttrs 添加的字段也会被重新编码，其开销与重新创建一遍这些 [slog.Logger] 相同。
+// 对性能有要求的场景，应当避免在添加字段的级别上使用 WithGroup。
+func NewSlogHandler(h slog.Handler, level slog.Leveler, source bool) slog.Handler {
+	return &slogHandler{root: h, h: h, level: level, source: source}
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	withStack := h.level != nil && r.Level >= h.level.Level() && r.PC != 0
+	if (!h.source && !withStack) || r.PC == 0 {
+		return h.h.Handle(ctx, r)
+	}
+
+	pc := r.PC
+	attrs := make([]slog.Attr, 0, 2)
+	if h.source {
+		r.PC = 0 // 防止 root 输出原始的源码位置
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		attrs = append(attrs, slog.Any(slog.SourceKey, &slog.Source{
+			Function: ParseFuncName(frame.Function).Short(),
+			File:     ShortenPath(frame.File),
+			Line:     frame.Line,
+		}))
+	}
+
+	if withStack {
+		pcs, _ := callers(0, 0)
+		if index := slices.Index(pcs, pc); index >= 0 {
+			pcs = pcs[index:]
+		}
+		attrs = append(attrs, StackAttr("stack", &StackTrace{pcs: pcs}))
+	}
+
+	if !h.grouped { // 没有分组时，记录中的字段即位于顶层。
+		r.AddAttrs(attrs...)
+		return h.h.Handle(ctx, r)
+	}
+
+	// 在顶层添加字段之后，再重新应用 WithAttrs 和 WithGroup。
+	hh := h.root.WithAttrs(attrs)
+	for _, op := range h.ops {
+		hh = op(hh)
+	}
+	return hh.Handle(ctx, r)
+}
+
+func (h *slogHandler) with(op func(slog.Handler) slog.Handler, group bool) *slogHandler {
+	return &slogHandler{
+		root:    h.root,
+		h:       op(h.h),
+		ops:     append(slices.Clip(h.ops), op),
+		grouped: h.grouped || group,
+		level:   h.level,
+		source:  h.source,
+	}
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(func(hh slog.Handler) slog.Handler { return hh.WithAttrs(attrs) }, false)
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(func(hh slog.Handler) slog.Handler { return hh.WithGroup(name) }, true)
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestStackAttr(t *testing.T) {
+	a := assert.New(t, false)
+
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewJSONHandler(buf, nil))
+	l.Info("msg", StackAttr("stack", NewStackTrace(0)))
+
+	var data map[string]any
+	a.NotError(json.Unmarshal(buf.Bytes(), &data))
+	stack, ok := data["stack"].(map[string]any)
+	a.True(ok).NotEmpty(stack)
+	frame, ok := stack["0"].(map[string]any)
+	a.True(ok).
+		Equal(frame["function"], "github.com/issue9/source.TestStackAttr").
+		Equal(frame["line"], 21.0)
+
+	buf.Reset()
+	a.NotPanic(func() { l.Info("msg", StackAttr("stack", nil)) })
+	data = nil
+	a.NotError(json.Unmarshal(buf.Bytes(), &data))
+	_, ok = data["stack"]
+	a.False(ok)
+}
+
+func TestNewSlogHandler(t *testing.T) {
+	a := assert.New(t, false)
+
+	buf := &bytes.Buffer{}
+	l := slog.New(NewSlogHandler(slog.NewJSONHandler(buf, nil), slog.LevelError, true))
+
+	l.Info("info", "k", "v")
+	var data map[string]any
+	a.NotError(json.Unmarshal(buf.Bytes(), &data))
+	a.Equal(data["k"], "v").Nil(data["stack"])
+	src, ok := data[slog.SourceKey].(map[string]any)
+	a.True(ok).
+		Equal(src["function"], "TestNewSlogHandler").
+		Equal(src["file"], "slog_test.go").
+		Equal(src["line"], 46.0)
+
+	buf.Reset()
+	l.With("with", 1).Error("error")
+	data = nil
+	a.NotError(json.Unmarshal(buf.Bytes(), &data))
+	a.Equal(data["with"], 1.0)
+	stack, ok := data["stack"].(map[string]any)
+	a.True(ok)
+	frame, ok := stack["0"].(map[string]any)
+	a.True(ok).
+		Equal(frame["function"], "github.com/issue9/source.TestNewSlogHandler").
+		Equal(frame["line"], 57.0)
+
+	// 不输出源码位置时，保留原始的源码位置
+	buf.Reset()
+	l = slog.New(NewSlogHandler(slog.NewJSONHandler(buf, &slog.HandlerOptions{AddSource: true}), slog.LevelInfo, false))
+	l.WithGroup("g").Info("info")
+	data = nil
+	a.NotError(json.Unmarshal(buf.Bytes(), &data))
+	src, ok = data[slog.SourceKey].(map[string]any)
+	a.True(ok).Equal(src["function"], "github.com/issue9/source.TestNewSlogHandler")
+	a.NotNil(data["stack"])
+	_, ok = data["g"]
+	a.False(ok) // 空的分组不输出
+
+	// 分组不影响添加的字段
+	buf.Reset()
+	l = slog.New(NewSlogHandler(slog.NewJSONHandler(buf, nil), slog.LevelError, true))
+	l.With("app", "x").WithGroup("req").With("id", 1).Error("error", "k", "v")
+	data = nil
+	a.NotError(json.Unmarshal(buf.Bytes(), &data))
+	a.Equal(data["app"], "x").NotNil(data["stack"])
+	src, ok = data[slog.SourceKey].(map[string]any)
+	a.True(ok).Equal(src["function"], "TestNewSlogHandler")
+	req, ok := data["req"].(map[string]any)
+	a.True(ok).
+		Equal(req, map[string]any{"id": 1.0, "k": "v"})
+
+	// 未添加字段时，分组依然有效
+	buf.Reset()
+	l.WithGroup("req").With("id", 1).Info("info")
+	data = nil
+	a.NotError(json.Unmarshal(buf.Bytes(), &data))
+	req, ok = data["req"].(map[string]any)
+	a.True(ok).Equal(req["id"], 1.0).NotNil(data[slog.SourceKey])
+
+	// 不输出任何内容
+	buf.Reset()
+	l = slog.New(NewSlogHandler(slog.NewJSONHandler(buf, nil), nil, false))
+	l.Error("error")
+	data = nil
+	a.NotError(json.Unmarshal(buf.Bytes(), &data))
+	a.Nil(data["stack"]).Nil(data[slog.SourceKey])
+}
@@ -9,9 +9,6 @@ import (
 	"bytes"
 	"io"
 	"path/filepath"
-	"runtime"
-	"strconv"
-	"strings"
 
 	"github.com/issue9/errwrap"
 )
@@ -19,55 +16,47 @@ import (
 // CurrentPath 获取`调用者`所在目录的路径
 //
 // 类似于部分语言的的 __DIR__ + "/" + path
+//
+// 如果使用了 -trimpath 进行编译，会尝试还原为本地的真实路径，
+// 无法还原时返回原始的路径，具体的错误信息可以通过 [SourceFile] 获取。
 func CurrentPath(path string) string {
-	_, fi, _, _ := runtime.Caller(1)
-	return filepath.Join(filepath.Dir(fi), path)
+	return filepath.Join(filepath.Dir(localFile(Caller(1).File)), path)
 }
 
 // CurrentDir 获取`调用者`所在的目录
 //
 // 相当于部分语言的 __DIR__
-func CurrentDir() string {
-	_, fi, _, _ := runtime.Caller(1)
-	return filepath.Dir(fi)
-}
+//
+// 如果使用了 -trimpath 进行编译，会尝试还原为本地的真实路径，
+// 无法还原时返回原始的路径，具体的错误信息可以通过 [SourceFile] 获取。
+func CurrentDir() string { return filepath.Dir(localFile(Caller(1).File)) }
 
 // CurrentFile 获取`调用者`所在的文件
 //
 // 相当于部分语言的 __FILE__
-func CurrentFile() string {
-	_, fi, _, _ := runtime.Caller(1)
-	return fi
-}
+//
+// 如果使用了 -trimpath 进行编译，会尝试还原为本地的真实路径，
+// 无法还原时返回原始的路径，具体的错误信息可以通过 [SourceFile] 获取。
+func CurrentFile() string { return localFile(Caller(1).File) }
 
 // CurrentLine 获取`调用者`所在的行
 //
 // 相当于部分语言的 __LINE__
-func CurrentLine() int {
-	_, _, line, _ := runtime.Caller(1)
-	return line
-}
+func CurrentLine() int { return Caller(1).Line }
 
 // CurrentLocation 获取`调用者`当前的位置信息
+//
+// path 与 [CurrentFile] 的返回值相同。
 func CurrentLocation() (path string, line int) {
-	_, path, line, _ = runtime.Caller(1)
-	return path, line
+	f := Caller(1)
+	return localFile(f.File), f.Line
 }
 
 // CurrentFunction 获取`调用者`所在的函数名
 //
-// 相当于部分语言的 __FUNCTION__
-func CurrentFunction() string {
-	pc, _, _, _ := runtime.Caller(1)
-	name := runtime.FuncForPC(pc).Name()
-
-	index := strings.LastIndexByte(name, '.')
-	if index > 0 {
-		name = name[index+1:]
-	}
-
-	return name
-}
+// 相当于部分语言的 __FUNCTION__，返回值不包含包名和接收者，
+// 如果在闭包中调用，返回的是闭包所在的函数名。
+func CurrentFunction() string { return Caller(1).Name }
 
 // Stack 返回调用堆栈信息
 //
@@ -90,31 +79,23 @@ func Stack(skip int, ignoreRuntime bool, msg ...any) string {
 //   - 1 表示 Stack 自身；
 //   - 2 表示 Stack 的调用者，以此类推；
 //
-// ignoreRuntime 表示是否不显示 runtime 下的系统调用信息；
+// ignoreRuntime 表示是否不显示 runtime 下的系统调用信息，与 [RuntimeFilter] 相同；
 // msg 表示需要输出的额外信息；
+//
+// 会输出完整的调用堆栈，如果需要限制输出的层数等，可以使用 [StackTrace.Dump]。
+// 与 [StackTrace] 相同，不包含 goroutine 的创建位置和祖先信息，需要时可以使用 [CurrentGoroutine]。
 func DumpStack(w io.Writer, skip int, ignoreRuntime bool, msg ...any) {
-	pc := make([]uintptr, 10)
-	n := runtime.Callers(skip, pc)
-	if n == 0 {
+	pcs, _ := callers(max(skip-1, 0), 0)
+	if len(pcs) == 0 {
 		return
 	}
 
-	pc = pc[:n]
-	frames := runtime.CallersFrames(pc)
-
 	buf := errwrap.Writer{Writer: w}
 	buf.Println(msg...)
-	for {
-		frame, more := frames.Next()
-		if !more {
-			break
-		}
-
-		if ignoreRuntime && strings.Contains(frame.File, "runtime/") {
-			continue
-		}
-
-		buf.WString(frame.Function).WByte('\n').
-			WByte('\t').WString(frame.File).WByte(':').WString(strconv.Itoa(frame.Line)).WByte('\n')
+	o := &options{}
+	if ignoreRuntime {
+		o.filters = append(o.filters, RuntimeFilter())
 	}
+	st := &StackTrace{pcs: pcs}
+	st.dump(w, o)
 }
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/issue9/errwrap"
+)
+
+const defaultDepth = 32
+
+// StackTrace 调用堆栈
+//
+// 创建时仅记录调用堆栈的 PC 值，在第一次需要时才会将其解析为 [Frame]。
+// 只包含当前 goroutine 的调用堆栈，不包含创建该 goroutine 的位置及其祖先的调用堆栈，
+// 这些信息只能通过 [CurrentGoroutine] 获取。
+type StackTrace struct {
+	pcs       []uintptr
+	truncated bool
+
+	once   sync.Once
+	frames []Frame
+}
+
+// NewStackTrace 获取当前的完整调用堆栈
+//
+// skip 需要忽略的调用层数：
+//
+//   - 0 表示调用 NewStackTrace 的函数；
+//   - 1 表示调用 NewStackTrace 的函数的调用者，以此类推；
+func NewStackTrace(skip int) *StackTrace {
+	pcs, truncated := callers(skip+1, 0)
+	return &StackTrace{pcs: pcs, truncated: truncated}
+}
+
+// NewStackTraceDepth 获取当前的调用堆栈且最多获取 depth 层
+//
+// skip 与 [NewStackTrace] 的相同；depth 为最多获取的层数，即 [StackTrace.PCs] 的最大长度，
+// 如果小于等于 0 表示不限制。超出的部分会被丢弃，可以通过 [StackTrace.Truncated] 判断是否有丢弃。
+func NewStackTraceDepth(skip, depth int) *StackTrace {
+	pcs, truncated := callers(skip+1, depth)
+	return &StackTrace{pcs: pcs, truncated: truncated}
+}
+
+// 获取调用堆栈的 PC 值
+//
+// skip 为 0 表示调用 callers 的函数；depth 表示最多获取的数量，小于等于 0 表示不限制。
+// 缓存会一直扩大直到能容纳整个调用堆栈或是达到 depth 为止。
+func callers(skip, depth int) (pcs []uintptr, truncated bool) {
+	size := defaultDepth
+	for {
+		if depth > 0 && size > depth {
+			size = depth + 1 // 多出一个用于判断是否被截断
+		}
+
+		pcs = make([]uintptr, size)
+		n := runtime.Callers(skip+2, pcs)
+		switch {
+		case depth > 0 && n > depth:
+			return pcs[:depth], true
+		case n < size:
+			return pcs[:n], false
+		}
+		size *= 2
+	}
+}
+
+// PCs 返回调用堆栈的原始 PC 值
+func (st *StackTrace) PCs() []uintptr { return st.pcs }
+
+// Truncated 是否因为 [NewStackTraceDepth] 的 depth 参数而丢弃了部分调用堆栈
+func (st *StackTrace) Truncated() bool { return st.truncated }
+
+// Frames 返回解析后的调用堆栈
+//
+// 内联的函数也会作为单独的一帧返回，所以其长度可能会大于 [StackTrace.PCs]。
+func (st *StackTrace) Frames() []Frame {
+	st.once.Do(func() {
+		if len(st.pcs) == 0 {
+			return
+		}
+
+		st.frames = make([]Frame, 0, len(st.pcs))
+		frames := runtime.CallersFrames(st.pcs)
+		for {
+			frame, more := frames.Next()
+			st.frames = append(st.frames, newRuntimeFrame(frame))
+			if !more {
+				break
+			}
+		}
+	})
+	return st.frames
+}
+
+func newRuntimeFrame(frame runtime.Frame) Frame {
+	f := newFrame(frame.Function, frame.File, frame.Line)
+	if frame.Entry != 0 && frame.PC >= frame.Entry {
+		f.Entry = frame.PC - frame.Entry
+	}
+	f.Inlined = frame.Func == nil && frame.Function != ""
+	return f
+}
+
+// Format 实现 [fmt.Formatter] 接口
+//
+//   - %s 和 %v 每一帧输出一行，格式为：函数名 (文件名:行号)，函数名不包含包名，文件名不包含目录；
+//   - %+v 与 [StackTrace.Dump] 的格式相同，每一帧输出完整的函数名和文件路径；
+func (st *StackTrace) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			st.Dump(s)
+			return
+		}
+		fallthrough
+	case 's':
+		buf := errwrap.Writer{Writer: s}
+		for _, f := range st.Frames() {
+			buf.WString(f.FuncName().Short()).
+				WString(" (").WString(filepath.Base(f.File)).WByte(':').WString(strconv.Itoa(f.Line)).WString(")\n")
+		}
+		if st.truncated {
+			buf.WString(truncatedMessage).WByte('\n')
+		}
+	default:
+		fmt.Fprintf(s, "%%!%c(*source.StackTrace)", verb)
+	}
+}
+
+// MarshalJSON 实现 [json.Marshaler] 接口
+//
+// 输出的内容为 [StackTrace.Frames] 的 JSON 数组。
+func (st *StackTrace) MarshalJSON() ([]byte, error) {
+	frames := st.Frames()
+	if frames == nil {
+		frames = []Frame{}
+	}
+	return json.Marshal(frames)
+}
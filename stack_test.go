@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestNewStackTrace(t *testing.T) {
+	a := assert.New(t, false)
+
+	st := NewStackTrace(0)
+	a.NotNil(st).NotEmpty(st.PCs())
+
+	frames := st.Frames()
+	a.NotEmpty(frames).
+		Equal(frames[0].Function, "github.com/issue9/source.TestNewStackTrace").
+		Equal(frames[0].Line, 19).
+		True(strings.HasSuffix(frames[0].File, "stack_test.go")).
+		True(frames[0].Entry > 0)
+	a.Equal(frames[len(frames)-1].Function, "runtime.goexit")
+
+	st = func() *StackTrace { return NewStackTrace(1) }()
+	a.Equal(st.Frames()[0].Function, "github.com/issue9/source.TestNewStackTrace")
+
+	st = NewStackTrace(1000)
+	a.Empty(st.PCs()).Empty(st.Frames())
+}
+
+func TestStackTrace_Format(t *testing.T) {
+	a := assert.New(t, false)
+
+	st := NewStackTrace(0)
+
+	s := fmt.Sprintf("%s", st)
+	a.True(strings.HasPrefix(s, "TestStackTrace_Format (stack_test.go:40)\n"), s)
+	a.Equal(fmt.Sprintf("%v", st), s)
+
+	s = fmt.Sprintf("%+v", st)
+	a.True(strings.HasPrefix(s, "github.com/issue9/source.TestStackTrace_Format\n\t"), s).
+		Contains(s, "stack_test.go:40\n")
+
+	a.Equal(fmt.Sprintf("%d", st), "%!d(*source.StackTrace)")
+}
+
+func TestStackTrace_MarshalJSON(t *testing.T) {
+	a := assert.New(t, false)
+
+	data, err := json.Marshal(NewStackTrace(0))
+	a.NotError(err)
+
+	var frames []Frame
+	a.NotError(json.Unmarshal(data, &frames)).NotEmpty(frames)
+	a.Equal(frames[0].Name, "TestStackTrace_MarshalJSON").
+		Equal(frames[0].Package, "github.com/issue9/source").
+		Equal(frames[0].Line, 56)
+
+	data, err = json.Marshal(NewStackTrace(1000))
+	a.NotError(err).Equal(string(data), "[]")
+}
@@ -0,0 +1,434 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/version"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const sumFile = "go.sum"
+
+// Sum go.sum 文件的内容
+type Sum struct {
+	Lines []*SumLine
+}
+
+// SumLine go.sum 中的一行记录
+type SumLine struct {
+	Module module.Version
+	GoMod  bool   // 是否为 go.mod 文件的哈希，即版本号带 /go.mod 后缀的记录。
+	Hash   string // 哈希值，比如 h1:xxx
+	Line   int    // 在文件中的行号，从 1 开始，由 [Sum.Add] 添加的为 0。
+}
+
+// SumIssueKind go.sum 问题的分类
+type SumIssueKind int8
+
+// 可能的 go.sum 问题
+const (
+	SumMissing   SumIssueKind = iota // go.mod 中的依赖项在 go.sum 中没有对应的哈希
+	SumStale                         // go.sum 中的哈希已经不再被 go.mod 需要
+	SumDuplicate                     // 完全相同的记录出现多次
+	SumConflict                      // 相同的模块版本存在不同的哈希值
+)
+
+// SumIssue go.mod 与 go.sum 之间的一致性问题
+type SumIssue struct {
+	Kind   SumIssueKind
+	Module module.Version
+	GoMod  bool
+	Lines  []int // 涉及的行号，对于 [SumMissing] 为空。
+}
+
+func (k SumIssueKind) String() string {
+	switch k {
+	case SumMissing:
+		return "missing"
+	case SumStale:
+		return "stale"
+	case SumDuplicate:
+		return "duplicate"
+	case SumConflict:
+		return "conflict"
+	default:
+		return "unknown"
+	}
+}
+
+func (i *SumIssue) String() string {
+	v := i.Module.Version
+	if i.GoMod {
+		v += "/go.mod"
+	}
+	return fmt.Sprintf("%s %s %s %v", i.Kind, i.Module.Path, v, i.Lines)
+}
+
+// SumFile 文件或目录 p 所在模块的 go.sum 内容
+//
+// 从当前目录开始依次向上查找 go.mod，返回与其同目录的 go.sum 文件位置，以及文件内容的解析。
+func SumFile(p string) (string, *Sum, error) {
+	dir, err := ModDir(p)
+	if err != nil {
+		return "", nil, err
+	}
+
+	path := filepath.Join(dir, sumFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sum, err := ParseSum(path, data)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, sum, nil
+}
+
+// ParseSum 解析 go.sum 的内容
+//
+// path 仅用于错误信息。
+func ParseSum(path string, data []byte) (*Sum, error) {
+	sum := &Sum{}
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for ln := 1; s.Scan(); ln++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: 格式错误", path, ln)
+		}
+
+		v, gomod := strings.CutSuffix(fields[1], "/go.mod")
+		sum.Lines = append(sum.Lines, &SumLine{
+			Module: module.Version{Path: fields[0], Version: v},
+			GoMod:  gomod,
+			Hash:   fields[2],
+			Line:   ln,
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return sum, nil
+}
+
+// Hashes 返回模块 m 的哈希值
+//
+// goMod 表示是否返回 go.mod 文件的哈希。
+func (s *Sum) Hashes(m module.Version, goMod bool) []string {
+	var hashes []string
+	for _, l := range s.Lines {
+		if l.Module == m && l.GoMod == goMod && !slices.Contains(hashes, l.Hash) {
+			hashes = append(hashes, l.Hash)
+		}
+	}
+	return hashes
+}
+
+// Modules 返回 go.sum 中出现的所有模块版本
+func (s *Sum) Modules() []module.Version {
+	mods := make([]module.Version, 0, len(s.Lines))
+	for _, l := range s.Lines {
+		if !slices.Contains(mods, l.Module) {
+			mods = append(mods, l.Module)
+		}
+	}
+	return mods
+}
+
+// Add 添加一条记录
+func (s *Sum) Add(m module.Version, goMod bool, hash string) {
+	s.Lines = append(s.Lines, &SumLine{Module: m, GoMod: goMod, Hash: hash})
+}
+
+// Format 将内容转换为 go.sum 的格式
+//
+// 与 go 命令的输出相同，按模块路径和版本排序，且会去掉重复的记录。
+func (s *Sum) Format() []byte {
+	lines := slices.Clone(s.Lines)
+	slices.SortStableFunc(lines, func(a, b *SumLine) int {
+		switch {
+		case a.Module.Path != b.Module.Path:
+			return strings.Compare(a.Module.Path, b.Module.Path)
+		case a.Module.Version != b.Module.Version:
+			if c := semver.Compare(a.Module.Version, b.Module.Version); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Module.Version, b.Module.Version)
+		case a.GoMod != b.GoMod:
+			if a.GoMod {
+				return 1
+			}
+			return -1
+		default:
+			return 0
+		}
+	})
+
+	buf := &bytes.Buffer{}
+	for i, l := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			if prev.Module == l.Module && prev.GoMod == l.GoMod && prev.Hash == l.Hash {
+				continue
+			}
+		}
+
+		buf.WriteString(l.Module.Path)
+		buf.WriteByte(' ')
+		buf.WriteString(l.Module.Version)
+		if l.GoMod {
+			buf.WriteString("/go.mod")
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(l.Hash)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// CheckSum 检测 p 所在模块的 go.mod 与 go.sum 之间的一致性
+//
+// 可检测的问题包括：
+//   - go.mod 中的依赖项在 go.sum 中缺少哈希，间接依赖仅要求存在 go.mod 的哈希；
+//   - go.sum 中不再被需要的哈希；
+//   - 重复的记录以及相同版本存在不同哈希的记录；
+//
+// 判断 go.mod 的哈希是否过期，需要从本地的模块缓存中读取依赖项的 go.mod 以构建完整的依赖图，
+// 如果缓存不完整，则只检测模块源码的哈希是否过期，整个过程不会访问网络，也不会调用 go 命令。
+// 主模块中的 replace 指令会应用到整个依赖图。
+//
+// go 指令低于 1.17（包括未指定）的模块，go.sum 中还会包含间接依赖的源码哈希，
+// 此时源码哈希以整个依赖图作为判断依据，依赖图不完整时不检测源码哈希是否过期。
+func CheckSum(p string) ([]*SumIssue, error) {
+	modPath, mod, err := ModFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	_, sum, err := SumFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		sum = &Sum{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return checkSum(mod, filepath.Dir(modPath), sum), nil
+}
+
+// PruneSum 去掉 p 所在模块的 go.sum 中不再需要以及重复的记录
+//
+// 如果存在相同版本的不同哈希值，无法确定哪个才是正确的，此时会返回错误且不会修改文件。
+func PruneSum(p string) error {
+	modPath, mod, err := ModFile(p)
+	if err != nil {
+		return err
+	}
+
+	path, sum, err := SumFile(p)
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		m     module.Version
+		goMod bool
+	}
+	removed := map[key]bool{}
+	for _, issue := range checkSum(mod, filepath.Dir(modPath), sum) {
+		switch issue.Kind {
+		case SumConflict:
+			return fmt.Errorf("%s: %s", path, issue)
+		case SumStale:
+			removed[key{m: issue.Module, goMod: issue.GoMod}] = true
+		}
+	}
+
+	sum.Lines = slices.DeleteFunc(sum.Lines, func(l *SumLine) bool { return removed[key{m: l.Module, goMod: l.GoMod}] })
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, sum.Format(), stat.Mode())
+}
+
+// dir 为 go.mod 所在的目录，用于查找被替换为本地目录的模块。
+func checkSum(mod *modfile.File, dir string, sum *Sum) []*SumIssue {
+	issues := make([]*SumIssue, 0, 10)
+
+	// 重复与冲突
+	type key struct {
+		m     module.Version
+		goMod bool
+	}
+	lines := map[key][]*SumLine{}
+	keys := make([]key, 0, len(sum.Lines))
+	for _, l := range sum.Lines {
+		k := key{m: l.Module, goMod: l.GoMod}
+		if _, found := lines[k]; !found {
+			keys = append(keys, k)
+		}
+		lines[k] = append(lines[k], l)
+	}
+	for _, k := range keys {
+		ls := lines[k]
+		if len(ls) < 2 {
+			continue
+		}
+
+		kind := SumDuplicate
+		if slices.ContainsFunc(ls, func(l *SumLine) bool { return l.Hash != ls[0].Hash }) {
+			kind = SumConflict
+		}
+		issue := &SumIssue{Kind: kind, Module: k.m, GoMod: k.goMod}
+		for _, l := range ls {
+			issue.Lines = append(issue.Lines, l.Line)
+		}
+		issues = append(issues, issue)
+	}
+
+	// 缺失
+	required := requiredModules(mod)
+	for _, r := range mod.Require {
+		m, ok := required[r.Mod]
+		if !ok { // 被替换为本地目录的模块，不需要哈希。
+			continue
+		}
+
+		if _, found := lines[key{m: m, goMod: true}]; !found {
+			issues = append(issues, &SumIssue{Kind: SumMissing, Module: m, GoMod: true})
+		}
+		if _, found := lines[key{m: m}]; !found && !r.Indirect {
+			issues = append(issues, &SumIssue{Kind: SumMissing, Module: m})
+		}
+	}
+
+	// 过期
+	//
+	// go 1.17 之前的模块没有对依赖图进行修剪，go.sum 中还包含了间接依赖中提供包的模块的源码哈希，
+	// 这些模块不一定出现在 go.mod 中，所以只能以整个依赖图作为判断依据。
+	direct := make(map[module.Version]bool, len(required))
+	for _, m := range required {
+		direct[m] = true
+	}
+	graph, complete := moduleGraph(mod, dir)
+	pruned := mod.Go != nil && version.Compare("go"+mod.Go.Version, "go1.17") >= 0
+	for _, k := range keys {
+		if k.goMod && (!complete || graph[k.m]) {
+			continue
+		}
+		if !k.goMod && (direct[k.m] || (!pruned && (!complete || graph[k.m]))) {
+			continue
+		}
+
+		issue := &SumIssue{Kind: SumStale, Module: k.m, GoMod: k.goMod}
+		for _, l := range lines[k] {
+			issue.Lines = append(issue.Lines, l.Line)
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+// 返回 go.mod 中 require 的模块与应用 replace 之后实际需要哈希的模块之间的对应关系
+//
+// 被替换为本地目录的模块不需要哈希，不会出现在返回值中。
+func requiredModules(mod *modfile.File) map[module.Version]module.Version {
+	required := make(map[module.Version]module.Version, len(mod.Require))
+	for _, r := range mod.Require {
+		if m, local := replaceModule(mod, r.Mod); local == "" {
+			required[r.Mod] = m
+		}
+	}
+	return required
+}
+
+// 对 m 应用 mod 中的 replace 指令
+//
+// 如果被替换为本地目录，local 为该目录，否则 local 为空，repl 为替换之后的模块。
+func replaceModule(mod *modfile.File, m module.Version) (repl module.Version, local string) {
+	index := slices.IndexFunc(mod.Replace, func(rep *modfile.Replace) bool {
+		return rep.Old.Path == m.Path && (rep.Old.Version == "" || rep.Old.Version == m.Version)
+	})
+	if index < 0 {
+		return m, ""
+	}
+
+	if n := mod.Replace[index].New; n.Version != "" {
+		return n, ""
+	}
+	return module.Version{}, mod.Replace[index].New.Path
+}
+
+// 根据本地模块缓存中的 go.mod 构建主模块 mod 的依赖图
+//
+// 主模块中的 replace 指令对整个依赖图有效，替换前后的模块都会被记录在依赖图中。
+// 被替换为本地目录的模块，从 dir 下的对应目录中读取其 go.mod。
+// complete 表示依赖图是否完整，如果缓存中缺少某个模块的 go.mod，则为 false。
+func moduleGraph(mod *modfile.File, dir string) (graph map[module.Version]bool, complete bool) {
+	graph = make(map[module.Version]bool, len(mod.Require))
+	visited := make(map[module.Version]bool, len(mod.Require))
+	complete = true
+
+	queue := make([]module.Version, 0, len(mod.Require))
+	for _, r := range mod.Require {
+		queue = append(queue, r.Mod)
+	}
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		if visited[m] {
+			continue
+		}
+		visited[m] = true
+
+		var f *modfile.File
+		var err error
+		switch repl, local := replaceModule(mod, m); {
+		case local != "":
+			if !filepath.IsAbs(local) {
+				local = filepath.Join(dir, local)
+			}
+			var data []byte
+			if data, err = os.ReadFile(filepath.Join(local, modFile)); err == nil {
+				f, err = modfile.ParseLax(filepath.Join(local, modFile), data, nil)
+			}
+		default:
+			graph[m] = true
+			graph[repl] = true
+			f, err = cachedModFile(repl.Path, repl.Version)
+		}
+		if err != nil {
+			complete = false
+			continue
+		}
+
+		for _, r := range f.Require {
+			if !visited[r.Mod] {
+				queue = append(queue, r.Mod)
+			}
+		}
+	}
+
+	return graph, complete
+}
@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"golang.org/x/mod/module"
+)
+
+func TestSumFile(t *testing.T) {
+	a := assert.New(t, false)
+
+	p, sum, err := SumFile("./")
+	a.NotError(err).NotNil(sum).True(filepath.Base(p) == "go.sum")
+	m := module.Version{Path: "github.com/issue9/assert/v4", Version: "v4.3.1"}
+	a.Length(sum.Hashes(m, false), 1).
+		Length(sum.Hashes(m, true), 1).
+		Contains(sum.Modules(), m)
+
+	p, sum, err = SumFile("./testdata/go.mod")
+	a.Error(err).Nil(sum).Empty(p)
+}
+
+func TestParseSum(t *testing.T) {
+	a := assert.New(t, false)
+
+	sum, err := ParseSum("go.sum", []byte("example.com/a v1.0.0 h1:a=\nexample.com/a v1.0.0/go.mod h1:b=\n\n"))
+	a.NotError(err).Length(sum.Lines, 2)
+	a.Equal(sum.Lines[1], &SumLine{Module: module.Version{Path: "example.com/a", Version: "v1.0.0"}, GoMod: true, Hash: "h1:b=", Line: 2})
+
+	sum, err = ParseSum("go.sum", []byte("example.com/a v1.0.0\n"))
+	a.ErrorString(err, "go.sum:1").Nil(sum)
+}
+
+func TestSum_Format(t *testing.T) {
+	a := assert.New(t, false)
+
+	sum := &Sum{}
+	sum.Add(module.Version{Path: "example.com/b", Version: "v1.0.0"}, true, "h1:b=")
+	sum.Add(module.Version{Path: "example.com/a", Version: "v1.10.0"}, false, "h1:a10=")
+	sum.Add(module.Version{Path: "example.com/a", Version: "v1.9.0"}, true, "h1:a9mod=")
+	sum.Add(module.Version{Path: "example.com/a", Version: "v1.9.0"}, false, "h1:a9=")
+	sum.Add(module.Version{Path: "example.com/b", Version: "v1.0.0"}, true, "h1:b=")
+
+	a.Equal(string(sum.Format()), `example.com/a v1.9.0 h1:a9=
+example.com/a v1.9.0/go.mod h1:a9mod=
+example.com/a v1.10.0 h1:a10=
+example.com/b v1.0.0/go.mod h1:b=
+`)
+}
+
+func writeSumModule(a *assert.Assertion, sum string) string {
+	dir := a.TB().TempDir()
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/app
+
+go 1.21
+
+require (
+	example.com/a v1.1.0
+	example.com/b v1.0.0 // indirect
+	example.com/local v1.0.0
+	example.com/old v1.0.0
+)
+
+replace example.com/local => ./local
+
+replace example.com/old => example.com/new v2.0.0
+`), os.ModePerm))
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.sum"), []byte(sum), os.ModePerm))
+	a.NotError(os.Mkdir(filepath.Join(dir, "local"), os.ModePerm))
+	a.NotError(os.WriteFile(filepath.Join(dir, "local", "go.mod"), []byte("module example.com/local\n"), os.ModePerm))
+	return dir
+}
+
+func TestCheckSum(t *testing.T) {
+	a := assert.New(t, false)
+	newModCache(a, map[string]string{
+		"example.com/a/@v/v1.1.0.mod":   "module example.com/a\n\nrequire example.com/c v1.0.0\n",
+		"example.com/b/@v/v1.0.0.mod":   "module example.com/b\n",
+		"example.com/c/@v/v1.0.0.mod":   "module example.com/c\n",
+		"example.com/new/@v/v2.0.0.mod": "module example.com/new\n",
+	})
+
+	dir := writeSumModule(a, `example.com/a v1.0.0 h1:a0=
+example.com/a v1.0.0/go.mod h1:a0mod=
+example.com/a v1.1.0/go.mod h1:a1mod=
+example.com/b v1.0.0/go.mod h1:bmod=
+example.com/b v1.0.0/go.mod h1:bmod=
+example.com/c v1.0.0/go.mod h1:cmod=
+example.com/new v2.0.0 h1:new=
+example.com/new v2.0.0/go.mod h1:newmod=
+example.com/new v2.0.0/go.mod h1:newmod2=
+`)
+
+	issues, err := CheckSum(dir)
+	a.NotError(err)
+	a.Equal(issues, []*SumIssue{
+		{Kind: SumDuplicate, Module: module.Version{Path: "example.com/b", Version: "v1.0.0"}, GoMod: true, Lines: []int{4, 5}},
+		{Kind: SumConflict, Module: module.Version{Path: "example.com/new", Version: "v2.0.0"}, GoMod: true, Lines: []int{8, 9}},
+		{Kind: SumMissing, Module: module.Version{Path: "example.com/a", Version: "v1.1.0"}},
+		{Kind: SumStale, Module: module.Version{Path: "example.com/a", Version: "v1.0.0"}, Lines: []int{1}},
+		{Kind: SumStale, Module: module.Version{Path: "example.com/a", Version: "v1.0.0"}, GoMod: true, Lines: []int{2}},
+	})
+
+	a.ErrorString(PruneSum(dir), "conflict")
+
+	// 缓存不完整，不检测 go.mod 哈希是否过期。
+	newModCache(a, nil)
+	issues, err = CheckSum(dir)
+	a.NotError(err).Length(issues, 4)
+}
+
+func TestPruneSum(t *testing.T) {
+	a := assert.New(t, false)
+	newModCache(a, map[string]string{
+		"example.com/a/@v/v1.1.0.mod":   "module example.com/a\n",
+		"example.com/b/@v/v1.0.0.mod":   "module example.com/b\n",
+		"example.com/new/@v/v2.0.0.mod": "module example.com/new\n",
+	})
+
+	dir := writeSumModule(a, `example.com/new v2.0.0/go.mod h1:newmod=
+example.com/a v1.0.0/go.mod h1:a0mod=
+example.com/a v1.1.0 h1:a1=
+example.com/a v1.1.0/go.mod h1:a1mod=
+example.com/b v1.0.0/go.mod h1:bmod=
+example.com/b v1.0.0/go.mod h1:bmod=
+example.com/new v2.0.0 h1:new=
+`)
+
+	a.NotError(PruneSum(dir))
+	data, err := os.ReadFile(filepath.Join(dir, "go.sum"))
+	a.NotError(err).Equal(string(data), `example.com/a v1.1.0 h1:a1=
+example.com/a v1.1.0/go.mod h1:a1mod=
+example.com/b v1.0.0/go.mod h1:bmod=
+example.com/new v2.0.0 h1:new=
+example.com/new v2.0.0/go.mod h1:newmod=
+`)
+
+	issues, err := CheckSum(dir)
+	a.NotError(err).Empty(issues)
+}
+
+func TestCheckSum_go116(t *testing.T) {
+	a := assert.New(t, false)
+	newModCache(a, map[string]string{
+		"example.com/a/@v/v1.0.0.mod": "module example.com/a\n\nrequire example.com/c v1.0.0\n",
+		"example.com/c/@v/v1.0.0.mod": "module example.com/c\n",
+	})
+
+	dir := a.TB().TempDir()
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/app
+
+go 1.16
+
+require example.com/a v1.0.0
+`), os.ModePerm))
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.sum"), []byte(`example.com/a v1.0.0 h1:a=
+example.com/a v1.0.0/go.mod h1:amod=
+example.com/c v1.0.0 h1:c=
+example.com/c v1.0.0/go.mod h1:cmod=
+example.com/d v1.0.0 h1:d=
+`), os.ModePerm))
+
+	// 间接依赖的源码哈希不能被当作过期
+	issues, err := CheckSum(dir)
+	a.NotError(err).Equal(issues, []*SumIssue{
+		{Kind: SumStale, Module: module.Version{Path: "example.com/d", Version: "v1.0.0"}, Lines: []int{5}},
+	})
+
+	// 缓存不完整，无法确定源码哈希是否过期。
+	newModCache(a, map[string]string{
+		"example.com/a/@v/v1.0.0.mod": "module example.com/a\n\nrequire example.com/c v1.0.0\n",
+	})
+	issues, err = CheckSum(dir)
+	a.NotError(err).Empty(issues)
+
+	a.NotError(PruneSum(dir))
+	data, err := os.ReadFile(filepath.Join(dir, "go.sum"))
+	a.NotError(err).Contains(string(data), "example.com/c v1.0.0 h1:c=\n")
+}
+
+func TestCheckSum_transitiveReplace(t *testing.T) {
+	a := assert.New(t, false)
+	newModCache(a, map[string]string{
+		"example.com/a/@v/v1.0.0.mod":    "module example.com/a\n\nrequire example.com/c v1.0.0\n",
+		"example.com/c/@v/v1.0.0.mod":    "module example.com/c\n",
+		"example.com/fork/@v/v1.1.0.mod": "module example.com/c\n\nrequire example.com/e v1.0.0\n",
+		"example.com/e/@v/v1.0.0.mod":    "module example.com/e\n",
+		"example.com/f/@v/v1.0.0.mod":    "module example.com/f\n",
+	})
+
+	dir := a.TB().TempDir()
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/app
+
+go 1.21
+
+require (
+	example.com/a v1.0.0
+	example.com/l v1.0.0
+)
+
+replace example.com/c => example.com/fork v1.1.0
+
+replace example.com/l => ./l
+`), os.ModePerm))
+	a.NotError(os.Mkdir(filepath.Join(dir, "l"), os.ModePerm))
+	a.NotError(os.WriteFile(filepath.Join(dir, "l", "go.mod"), []byte("module example.com/l\n\nrequire example.com/f v1.0.0\n"), os.ModePerm))
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.sum"), []byte(`example.com/a v1.0.0 h1:a=
+example.com/a v1.0.0/go.mod h1:amod=
+example.com/e v1.0.0/go.mod h1:emod=
+example.com/f v1.0.0/go.mod h1:fmod=
+example.com/fork v1.1.0/go.mod h1:forkmod=
+`), os.ModePerm))
+
+	// 仅通过间接依赖到达的替换目标以及本地目录中的依赖项都不应该被当作过期
+	issues, err := CheckSum(dir)
+	a.NotError(err).Empty(issues)
+}
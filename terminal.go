@@ -0,0 +1,255 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/issue9/errwrap"
+)
+
+// 超链接的格式
+//
+// 可用于 [WithLink]，其中 {file} 为以 / 开头的绝对路径，{line} 为行号。
+const (
+	FileLink   = "file://{file}"
+	VSCodeLink = "vscode://file{file}:{line}"
+)
+
+const defaultTerminalWidth = 80
+
+// 各类帧对应的 SGR 参数
+var kindColors = map[Kind]string{
+	KindApp:        "1;32", // 加粗的绿色
+	KindDependency: "36",   // 青色
+	KindStd:        "33",   // 黄色
+	KindRuntime:    "2",    // 暗淡
+}
+
+const (
+	dimColor     = "2"
+	currentColor = "1"
+)
+
+// WithLink 指定 [StackTrace.Render] 中超链接的格式
+//
+// 格式中可以使用 {file} 和 {line} 两个占位符，比如 [FileLink] 和 [VSCodeLink]。
+// 默认为 [FileLink]，如果为空则不输出超链接。
+func WithLink(format string) Option { return func(o *options) { o.link = &format } }
+
+// ForceTerminal 让 [StackTrace.Render] 始终以终端的格式输出
+//
+// 即使 w 不是终端，依然输出颜色和超链接，但是 NO_COLOR 依然有效。
+func ForceTerminal() Option { return func(o *options) { o.terminal = true } }
+
+// Render 以适合终端的格式将调用堆栈写入 w
+//
+// 每一帧输出一行，函数名和文件位置分为两列对齐，并根据 [Frame.Classify] 的分类使用不同的颜色。
+// 文件位置以 [ShortenPath] 的格式显示，并通过 OSC 8 序列链接到源码文件，格式由 [WithLink] 指定。
+//
+// 如果 w 不是终端，则与 [StackTrace.Dump] 的输出相同。
+// 设置了环境变量 NO_COLOR 时不输出颜色；终端的宽度从环境变量 COLUMNS 或是 w 中获取。
+func (st *StackTrace) Render(w io.Writer, opt ...Option) error {
+	o := buildOptions(opt...)
+
+	if !o.terminal && !isTerminal(w) {
+		return st.dump(w, o)
+	}
+
+	t := &terminal{
+		color: os.Getenv("NO_COLOR") == "",
+		link:  FileLink,
+		width: terminalWidth(w),
+	}
+	if o.link != nil {
+		t.link = *o.link
+	}
+	return t.render(w, st, o)
+}
+
+type terminal struct {
+	color bool
+	link  string
+	width int
+}
+
+type terminalRow struct {
+	frame Frame
+	kind  Kind
+	fn    string
+	loc   string
+	msg   string // 非空表示该行为提示信息
+}
+
+func (t *terminal) render(w io.Writer, st *StackTrace, o *options) error {
+	filter := AnyFilter(o.filters...)
+	rows := make([]terminalRow, 0, len(st.Frames()))
+
+	var depth, more, filtered int
+	addFiltered := func() {
+		if o.collapse && filtered > 0 {
+			rows = append(rows, terminalRow{msg: "... " + strconv.Itoa(filtered) + " frames filtered"})
+		}
+		filtered = 0
+	}
+
+	fnWidth := 0
+	for _, frame := range st.Frames() {
+		if filter(&frame) {
+			filtered++
+			continue
+		}
+
+		if o.maxDepth > 0 && depth >= o.maxDepth {
+			more++
+			continue
+		}
+
+		addFiltered()
+		depth++
+		kind, _ := frame.Classify()
+		row := terminalRow{
+			frame: frame,
+			kind:  kind,
+			fn:    frame.FuncName().String(),
+			loc:   frame.ShortFile() + ":" + strconv.Itoa(frame.Line),
+		}
+		rows = append(rows, row)
+		fnWidth = max(fnWidth, utf8.RuneCountInString(row.fn))
+	}
+
+	if more > 0 {
+		rows = append(rows, terminalRow{msg: "... " + strconv.Itoa(more) + " more frames"})
+	} else {
+		addFiltered()
+	}
+	if st.truncated {
+		rows = append(rows, terminalRow{msg: truncatedMessage})
+	}
+
+	// 函数名过长时，位置信息换到下一行输出。
+	fnWidth = min(fnWidth, t.width/2)
+
+	buf := errwrap.Writer{Writer: w}
+	for _, row := range rows {
+		if row.msg != "" {
+			t.sgr(&buf, dimColor, row.msg)
+			buf.WByte('\n')
+			continue
+		}
+
+		t.sgr(&buf, kindColors[row.kind], row.fn)
+		if n := utf8.RuneCountInString(row.fn); n > fnWidth {
+			buf.WByte('\n').WString(strings.Repeat(" ", fnWidth+2))
+		} else {
+			buf.WString(strings.Repeat(" ", fnWidth-n+2))
+		}
+		t.hyperlink(&buf, row.frame, row.loc)
+		buf.WByte('\n')
+
+		if o.context > 0 {
+			t.writeContext(&buf, row.frame, o.context)
+		}
+	}
+
+	return buf.Err
+}
+
+// 输出带颜色的文本
+func (t *terminal) sgr(buf *errwrap.Writer, color, text string) {
+	if !t.color || color == "" {
+		buf.WString(text)
+		return
+	}
+	buf.WString("\x1b[").WString(color).WByte('m').WString(text).WString("\x1b[0m")
+}
+
+// 输出 OSC 8 超链接
+func (t *terminal) hyperlink(buf *errwrap.Writer, frame Frame, text string) {
+	link := t.url(frame)
+	if link == "" {
+		t.sgr(buf, dimColor, text)
+		return
+	}
+
+	buf.WString("\x1b]8;;").WString(link).WString("\x1b\\")
+	t.sgr(buf, dimColor, text)
+	buf.WString("\x1b]8;;\x1b\\")
+}
+
+func (t *terminal) url(frame Frame) string {
+	if t.link == "" || frame.File == "" {
+		return ""
+	}
+
+	file := localFile(frame.File)
+	if !filepath.IsAbs(file) {
+		return ""
+	}
+	file = filepath.ToSlash(file)
+	if file[0] != '/' { // Windows 下的 C:/
+		file = "/" + file
+	}
+
+	return strings.NewReplacer(
+		"{file}", (&url.URL{Path: file}).EscapedPath(),
+		"{line}", strconv.Itoa(frame.Line),
+	).Replace(t.link)
+}
+
+func (t *terminal) writeContext(buf *errwrap.Writer, frame Frame, n int) {
+	lines, err := frame.Context(n)
+	if err != nil || len(lines) == 0 {
+		return
+	}
+
+	width := len(strconv.Itoa(lines[len(lines)-1].Line))
+	for _, l := range lines {
+		num := strconv.Itoa(l.Line)
+		num = strings.Repeat(" ", width-len(num)) + num
+		buf.WString("    ")
+		if l.Current {
+			t.sgr(buf, currentColor, "> "+num+" | "+l.Text)
+		} else {
+			t.sgr(buf, dimColor, "  "+num+" |")
+			buf.WByte(' ').WString(l.Text)
+		}
+		buf.WByte('\n')
+	}
+}
+
+// w 是否为终端
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// 终端的宽度
+//
+// 优先使用环境变量 COLUMNS，其次从 w 中获取，都无法获取时返回 80。
+func terminalWidth(w io.Writer) int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+
+	if f, ok := w.(*os.File); ok {
+		if n := fileWidth(f); n > 0 {
+			return n
+		}
+	}
+
+	return defaultTerminalWidth
+}
@@ -0,0 +1,11 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package source
+
+import "os"
+
+func fileWidth(*os.File) int { return 0 }
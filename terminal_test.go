@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestStackTrace_Render(t *testing.T) {
+	a := assert.New(t, false)
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("COLUMNS", "200")
+
+	var st *StackTrace
+	recursive(3, func() { st = NewStackTrace(0) })
+
+	// 非终端
+	plain := &bytes.Buffer{}
+	a.NotError(st.Dump(plain))
+	buf := &bytes.Buffer{}
+	a.NotError(st.Render(buf))
+	a.Equal(buf.String(), plain.String())
+
+	buf.Reset()
+	a.NotError(st.Render(buf, ForceTerminal()))
+	s := buf.String()
+	a.Contains(s, "\x1b[1;32mgithub.com/issue9/source.recursive\x1b[0m").
+		Contains(s, "\x1b[2mruntime.goexit\x1b[0m").
+		Contains(s, "\x1b]8;;file://").
+		Contains(s, "\x1b[2mterminal_test.go:").
+		NotContains(s, "\n\t")
+
+	// 列对齐
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	a.Length(lines, len(st.Frames()))
+	for _, l := range lines {
+		a.Equal(visibleIndex(l, "\x1b]8;;"), visibleIndex(lines[0], "\x1b]8;;"), l)
+	}
+	a.True(visibleIndex(lines[0], "\x1b]8;;") > 0)
+
+	buf.Reset()
+	a.NotError(st.Render(buf, ForceTerminal(), WithLink(VSCodeLink), MaxDepth(2)))
+	s = buf.String()
+	a.Contains(s, "\x1b]8;;vscode://file/").
+		Contains(s, "/terminal_test.go:"+strconv.Itoa(st.Frames()[0].Line)+"\x1b\\").
+		Contains(s, "\x1b[2m... "+strconv.Itoa(len(st.Frames())-2)+" more frames\x1b[0m\n")
+
+	// NO_COLOR
+	t.Setenv("NO_COLOR", "1")
+	buf.Reset()
+	a.NotError(st.Render(buf, ForceTerminal(), WithLink(""), WithContext(1), WithFilter(RuntimeFilter()), Collapse()))
+	s = buf.String()
+	a.NotContains(s, "\x1b").
+		Contains(s, "github.com/issue9/source.recursive  ").
+		Contains(s, "    > "+strconv.Itoa(st.Frames()[0].Line)+" | \trecursive(3, func() { st = NewStackTrace(0) })\n").
+		Contains(s, "... 1 frames filtered\n")
+
+	// 函数名过长时换行
+	t.Setenv("COLUMNS", "20")
+	buf.Reset()
+	a.NotError(st.Render(buf, ForceTerminal(), WithLink("")))
+	s = buf.String()
+	a.Contains(s, "github.com/issue9/source.recursive\n            dump_test.go:")
+}
+
+// 去除转义序列之后 sub 在 s 中的位置
+func visibleIndex(s, sub string) int {
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return -1
+	}
+	s = s[:i]
+
+	n := 0
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, "\x1b["):
+			s = s[strings.IndexByte(s, 'm')+1:]
+		case strings.HasPrefix(s, "\x1b]8;;"):
+			s = s[strings.Index(s, "\x1b\\")+2:]
+		default:
+			s = s[1:]
+			n++
+		}
+	}
+	return n
+}
+
+func TestIsTerminal(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.False(isTerminal(&bytes.Buffer{}))
+
+	f, err := os.CreateTemp(t.TempDir(), "tty")
+	a.NotError(err).NotNil(f)
+	defer f.Close()
+	a.False(isTerminal(f))
+}
+
+func TestTerminalWidth(t *testing.T) {
+	a := assert.New(t, false)
+
+	t.Setenv("COLUMNS", "120")
+	a.Equal(terminalWidth(&bytes.Buffer{}), 120)
+
+	t.Setenv("COLUMNS", "")
+	a.Equal(terminalWidth(&bytes.Buffer{}), defaultTerminalWidth)
+
+	f, err := os.CreateTemp(t.TempDir(), "tty")
+	a.NotError(err).NotNil(f)
+	defer f.Close()
+	a.Equal(terminalWidth(f), defaultTerminalWidth)
+}
@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package source
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// 通过 ioctl 获取终端的宽度，失败时返回 0。
+func fileWidth(f *os.File) int {
+	var ws struct{ Row, Col, X, Y uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0
+	}
+	return int(ws.Col)
+}
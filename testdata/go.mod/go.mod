@@ -3,6 +3,9 @@ module github.com/issue9/source/mod
 require (
     github.com/issue9/source v1.0.0
     github.com/issue9/web/v2 v2.0.0
+    github.com/issue9/errwrap v0.3.3
 )
 
 replace github.com/issue9/source => ../../
+
+replace github.com/issue9/errwrap => github.com/issue9/not-exists v1.0.0
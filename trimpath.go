@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// SourceError 无法找到源码文件时返回的错误
+//
+// 可以通过 [errors.Is] 与 [fs.ErrNotExist] 进行匹配。
+type SourceError struct {
+	File  string   // 需要查找的文件，即运行时记录的文件路径。
+	Tried []string // 尝试过的路径
+}
+
+func (e *SourceError) Error() string {
+	if len(e.Tried) == 0 {
+		return "源码不可用：" + e.File
+	}
+	return "源码不可用：" + e.File + "，尝试过的路径：" + strings.Join(e.Tried, "、")
+}
+
+// Is 可以与 [fs.ErrNotExist] 进行匹配
+func (e *SourceError) Is(target error) bool { return target == os.ErrNotExist }
+
+// IsTrimmed 运行时记录的文件路径 file 是否为 -trimpath 处理过的路径
+//
+// 使用 -trimpath 编译时，运行时记录的文件路径不再是绝对路径，而是以下几种形式：
+//   - 依赖项：github.com/issue9/assert/v4@v4.3.1/assert.go；
+//   - 主模块或是被 replace 到本地的模块：github.com/issue9/source/source.go；
+//   - 标准库：encoding/json/decode.go；
+func IsTrimmed(file string) bool {
+	return file != "" && !filepath.IsAbs(file) && !strings.HasPrefix(file, "/")
+}
+
+// SourceFile 返回运行时记录的文件路径 file 在本地对应的源码文件
+//
+// 如果 file 为绝对路径，则仅检测其是否存在；
+// 如果 file 是 -trimpath 处理过的路径（参考 [IsTrimmed]），
+// 则根据 [debug.ReadBuildInfo]、当前工作目录所在模块的 go.mod 以及本地的模块缓存还原为绝对路径。
+//
+// 当文件不存在时，返回 [*SourceError]。
+func SourceFile(file string) (string, error) {
+	if !IsTrimmed(file) {
+		if _, err := os.Stat(file); err != nil {
+			return "", &SourceError{File: file, Tried: []string{file}}
+		}
+		return file, nil
+	}
+
+	se := &SourceError{File: file}
+	for _, p := range trimmedCandidates(file) {
+		se.Tried = append(se.Tried, p)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", se
+}
+
+// 返回 -trimpath 处理过的路径 file 可能对应的所有本地路径
+func trimmedCandidates(file string) []string {
+	file = filepath.ToSlash(file)
+	paths := make([]string, 0, 3)
+
+	// github.com/issue9/assert/v4@v4.3.1/assert.go
+	if at := strings.IndexByte(file, '@'); at > 0 {
+		modPath, version := file[:at], file[at+1:]
+		var rest string
+		if slash := strings.IndexByte(version, '/'); slash > 0 {
+			version, rest = version[:slash], version[slash:]
+		}
+		if p, err := escapePath(modPath, version, rest); err == nil {
+			paths = append(paths, filepath.Join(pkgSource, filepath.FromSlash(p)))
+		}
+		return paths
+	}
+
+	mainPath, mainDir := mainModule()
+
+	if mainPath != "" && (file == mainPath || strings.HasPrefix(file, mainPath+"/")) {
+		paths = append(paths, filepath.Join(mainDir, filepath.FromSlash(strings.TrimPrefix(file, mainPath))))
+	}
+
+	if first, _, _ := strings.Cut(file, "/"); strings.IndexByte(first, '.') < 0 { // 标准库
+		paths = append(paths, filepath.Join(stdSource, filepath.FromSlash(file)))
+	}
+
+	// 在 go.mod 中查找，可以处理被 replace 到本地的模块
+	if mainDir != "" {
+		dir, base := path.Split(file)
+		if d, err := PkgSourceDir(strings.TrimSuffix(dir, "/"), mainDir, true); err == nil {
+			paths = append(paths, filepath.Join(d, base))
+		}
+	}
+
+	// 构建信息中的依赖项
+	if info, ok := buildInfo(); ok {
+		var dep *debug.Module
+		for _, d := range info.Deps {
+			if strings.HasPrefix(file, d.Path+"/") && (dep == nil || len(d.Path) > len(dep.Path)) {
+				dep = d
+			}
+		}
+
+		if dep != nil {
+			rest := strings.TrimPrefix(file, dep.Path)
+			if r := dep.Replace; r != nil {
+				if r.Version == "" { // 指向本地
+					p := r.Path
+					if !filepath.IsAbs(p) && mainDir != "" {
+						p = filepath.Join(mainDir, p)
+					}
+					paths = append(paths, filepath.Join(p, filepath.FromSlash(rest)))
+				} else if p, err := escapePath(r.Path, r.Version, rest); err == nil {
+					paths = append(paths, filepath.Join(pkgSource, filepath.FromSlash(p)))
+				}
+			} else if p, err := escapePath(dep.Path, dep.Version, rest); err == nil {
+				paths = append(paths, filepath.Join(pkgSource, filepath.FromSlash(p)))
+			}
+		}
+	}
+
+	return paths
+}
+
+var buildInfo = sync.OnceValues(debug.ReadBuildInfo)
+
+// 主模块的导入路径及其所在的目录
+//
+// 导入路径优先从 [debug.ReadBuildInfo] 中获取，目录则从当前工作目录开始向上查找 go.mod 确定。
+var mainModule = sync.OnceValues(func() (modPath, dir string) {
+	if info, ok := buildInfo(); ok {
+		modPath = info.Main.Path
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return modPath, ""
+	}
+
+	for {
+		p, mod, err := ModFile(wd)
+		if err != nil {
+			return modPath, ""
+		}
+
+		dir = filepath.Dir(p)
+		if modPath == "" || (mod.Module != nil && mod.Module.Mod.Path == modPath) {
+			if mod.Module != nil {
+				modPath = mod.Module.Mod.Path
+			}
+			return modPath, dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return modPath, ""
+		}
+		wd = parent
+	}
+})
+
+// 将 file 还原为本地路径，如果无法还原则返回 file 本身。
+func localFile(file string) string {
+	if !IsTrimmed(file) {
+		return file
+	}
+	if p, err := SourceFile(file); err == nil {
+		return p
+	}
+	return file
+}
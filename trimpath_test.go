@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestIsTrimmed(t *testing.T) {
+	a := assert.New(t, false)
+
+	abs, err := filepath.Abs("./source.go")
+	a.NotError(err)
+
+	a.False(IsTrimmed(abs)).
+		False(IsTrimmed("")).
+		False(IsTrimmed("/usr/local/go/src/fmt/print.go")).
+		True(IsTrimmed("fmt/print.go")).
+		True(IsTrimmed("github.com/issue9/source/source.go")).
+		True(IsTrimmed("github.com/issue9/assert/v4@v4.3.1/assert.go"))
+}
+
+func TestSourceFile(t *testing.T) {
+	a := assert.New(t, false)
+
+	abs, err := filepath.Abs("./source.go")
+	a.NotError(err)
+
+	p, err := SourceFile(abs)
+	a.NotError(err).Equal(p, abs)
+
+	// 主模块
+	p, err = SourceFile("github.com/issue9/source/source.go")
+	a.NotError(err).Equal(p, abs)
+
+	p, err = SourceFile("github.com/issue9/source/codegen/codegen.go")
+	a.NotError(err).Equal(p, filepath.Join(filepath.Dir(abs), "codegen", "codegen.go"))
+
+	// 依赖项
+	p, err = SourceFile("github.com/issue9/assert/v4@v4.3.1/assert.go")
+	a.NotError(err).Equal(p, filepath.Join(pkgSource, "github.com", "issue9", "assert", "v4@v4.3.1", "assert.go"))
+
+	// 构建信息中的依赖项
+	p, err = SourceFile("github.com/issue9/errwrap/writer.go")
+	a.NotError(err).FileExists(p)
+
+	// 标准库
+	p, err = SourceFile("encoding/json/decode.go")
+	a.NotError(err).Equal(p, filepath.Join(stdSource, "encoding", "json", "decode.go"))
+
+	// 不存在
+	p, err = SourceFile("github.com/issue9/source/not-exists.go")
+	a.ErrorIs(err, fs.ErrNotExist).Empty(p)
+	se, ok := err.(*SourceError)
+	a.True(ok).Equal(se.File, "github.com/issue9/source/not-exists.go").NotEmpty(se.Tried).
+		Contains(se.Error(), "not-exists.go")
+
+	p, err = SourceFile("github.com/issue9/not-exists@v1.0.0/x.go")
+	a.ErrorIs(err, fs.ErrNotExist).Empty(p)
+
+	p, err = SourceFile(filepath.Join(filepath.Dir(abs), "not-exists.go"))
+	a.ErrorIs(err, fs.ErrNotExist).Empty(p)
+}
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+var majorDir = regexp.MustCompile(`^v[0-9]+$`)
+
+// Update 依赖项在本地缓存中可用的新版本
+type Update struct {
+	Module module.Version
+
+	Patch string // 与当前版本的 major.minor 相同的最新版本，如果没有则为空。
+	Minor string // 与当前版本的 major 相同，但 minor 更高的最新版本，如果没有则为空。
+
+	// 更高的主版本
+	//
+	// 包括以 /vN 或是 gopkg.in 的 .vN 为后缀的模块路径，以及同一路径下带 +incompatible 的版本，
+	// 每个主版本只包含其最新的版本，按版本从低到高排列。
+	Major []module.Version
+}
+
+// Updates 返回 p 所在模块的依赖项在本地缓存中可用的新版本
+//
+// 会从 $GOPATH/pkg/mod/cache/download 下的 list 和 .mod 文件以及 $GOPATH/pkg/mod 下已解压的目录中查找版本，
+// 整个过程不会访问网络。预发布版本和伪版本不会作为候选版本。
+//
+// 返回值与 go.mod 中的 require 一一对应，即使没有可用的新版本也会包含在返回值中。
+func Updates(p string) ([]*Update, error) {
+	_, mod, err := ModFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make([]*Update, 0, len(mod.Require))
+	for _, r := range mod.Require {
+		u, err := update(r.Mod)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, u)
+	}
+	return updates, nil
+}
+
+func update(m module.Version) (*Update, error) {
+	u := &Update{Module: m}
+
+	versions, err := localVersions(m.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	major := semver.Major(m.Version)
+	majorMinor := semver.MajorMinor(m.Version)
+	incompatible := map[string]string{} // 同一路径下更高的主版本
+	for _, v := range versions {
+		if semver.Compare(v, m.Version) <= 0 {
+			continue
+		}
+
+		switch {
+		case semver.MajorMinor(v) == majorMinor:
+			if u.Patch == "" || semver.Compare(v, u.Patch) > 0 {
+				u.Patch = v
+			}
+		case semver.Major(v) == major:
+			if u.Minor == "" || semver.Compare(v, u.Minor) > 0 {
+				u.Minor = v
+			}
+		default:
+			if mv := semver.Major(v); incompatible[mv] == "" || semver.Compare(v, incompatible[mv]) > 0 {
+				incompatible[mv] = v
+			}
+		}
+	}
+	for _, v := range incompatible {
+		u.Major = append(u.Major, module.Version{Path: m.Path, Version: v})
+	}
+
+	paths, err := majorPaths(m.Path, m.Version)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		vs, err := localVersions(p)
+		if err != nil {
+			return nil, err
+		}
+
+		var latest string
+		for _, v := range vs {
+			if latest == "" || semver.Compare(v, latest) > 0 {
+				latest = v
+			}
+		}
+		if latest != "" && semver.Compare(latest, m.Version) > 0 {
+			u.Major = append(u.Major, module.Version{Path: p, Version: latest})
+		}
+	}
+
+	slices.SortFunc(u.Major, func(a, b module.Version) int { return semver.Compare(a.Version, b.Version) })
+
+	return u, nil
+}
+
+// 返回本地缓存中 modPath 的所有正式版本
+func localVersions(modPath string) ([]string, error) {
+	versions, err := cachedVersions(modPath)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := cacheDownloadDir(modPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "list"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		if v := strings.TrimSpace(s.Text()); v != "" {
+			versions = append(versions, v)
+		}
+	}
+
+	// 已解压的目录：$GOPATH/pkg/mod/github.com/issue9/assert/v4@v4.3.1
+	p, err := module.EscapePath(modPath)
+	if err != nil {
+		return nil, err
+	}
+	parent, base := filepath.Split(filepath.Join(pkgSource, p))
+	entries, err := os.ReadDir(parent)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if v, found := strings.CutPrefix(e.Name(), base+"@"); found && e.IsDir() {
+			if v, err = module.UnescapeVersion(v); err == nil {
+				versions = append(versions, v)
+			}
+		}
+	}
+
+	versions = slices.DeleteFunc(versions, func(v string) bool {
+		return !semver.IsValid(v) || semver.Prerelease(v) != "" || module.CheckPathMajor(v, pathMajor(modPath)) != nil
+	})
+	slices.SortFunc(versions, semver.Compare)
+	return slices.Compact(versions), nil
+}
+
+func pathMajor(modPath string) string {
+	_, major, _ := module.SplitPathVersion(modPath)
+	return major
+}
+
+// 返回本地缓存中与 modPath 属于同一项目，但主版本更高的模块路径
+func majorPaths(modPath, version string) ([]string, error) {
+	prefix, major, ok := module.SplitPathVersion(modPath)
+	if !ok {
+		return nil, nil
+	}
+
+	current := 1
+	incompatible := false // v2.0.0+incompatible 之类的版本，同主版本的 /v2 也属于更新。
+	if major != "" {
+		current, _ = strconv.Atoi(strings.TrimLeft(major, "/.v"))
+	} else if n, err := strconv.Atoi(strings.TrimPrefix(semver.Major(version), "v")); err == nil && n > current {
+		current = n
+		incompatible = semver.Build(version) == "+incompatible"
+	}
+
+	gopkg := strings.HasPrefix(modPath, "gopkg.in/")
+	dir, base := prefix, ""
+	if gopkg {
+		dir, base = path.Split(prefix)
+	}
+	escaped, err := module.EscapePath(strings.TrimSuffix(dir, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, 2)
+	for _, root := range []string{filepath.Join(pkgSource, "cache", "download"), pkgSource} {
+		entries, err := os.ReadDir(filepath.Join(root, escaped))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+
+			name, _, _ := strings.Cut(e.Name(), "@") // 已解压的目录带版本号
+			var p, v string
+			if gopkg {
+				var found bool
+				if v, found = strings.CutPrefix(name, base+"."); !found {
+					continue
+				}
+				p = path.Join(dir, name)
+			} else {
+				v = name
+				p = prefix + "/" + name
+			}
+
+			if !majorDir.MatchString(v) {
+				continue
+			}
+			if n, err := strconv.Atoi(v[1:]); err != nil || n < current || (n == current && !incompatible) || n < 2 {
+				continue
+			}
+			if !slices.Contains(paths, p) {
+				paths = append(paths, p)
+			}
+		}
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2020-2024 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"golang.org/x/mod/module"
+)
+
+func TestUpdates(t *testing.T) {
+	a := assert.New(t, false)
+	root := newModCache(a, map[string]string{
+		"example.com/a/@v/list":                      "v1.0.0\nv1.0.1\nv1.1.0\nv1.2.0-beta.1\nv2.0.0+incompatible\nv2.1.0+incompatible\n",
+		"example.com/a/@v/v1.0.2.mod":                "module example.com/a\n",
+		"example.com/a/v3/@v/v3.0.0.mod":             "module example.com/a/v3\n",
+		"example.com/a/v3/@v/v3.1.0.mod":             "module example.com/a/v3\n",
+		"example.com/a/v4/@v/v4.0.0-rc.1.mod":        "module example.com/a/v4\n",
+		"example.com/!upper/v2/@v/v2.0.0.mod":        "module example.com/Upper/v2\n",
+		"gopkg.in/yaml.v2/@v/v2.4.0.mod":             "module gopkg.in/yaml.v2\n",
+		"gopkg.in/yaml.v3/@v/v3.0.1.mod":             "module gopkg.in/yaml.v3\n",
+		"example.com/inc/@v/v2.0.0+incompatible.mod": "module example.com/inc\n",
+		"example.com/inc/@v/v2.0.1+incompatible.mod": "module example.com/inc\n",
+		"example.com/inc/v2/@v/v2.2.0.mod":           "module example.com/inc/v2\n",
+		"example.com/inc/v3/@v/v3.0.0.mod":           "module example.com/inc/v3\n",
+	})
+	// 已解压的目录
+	a.NotError(os.MkdirAll(filepath.Join(root, "example.com", "!upper@v1.5.0"), os.ModePerm))
+	a.NotError(os.MkdirAll(filepath.Join(root, "example.com", "!upper", "v3@v3.0.0"), os.ModePerm))
+
+	dir := t.TempDir()
+	a.NotError(os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/app
+
+require (
+	example.com/a v1.0.0
+	example.com/Upper v1.0.0
+	gopkg.in/yaml.v2 v2.2.0
+	example.com/inc v2.0.0+incompatible
+	example.com/none v1.0.0
+)
+`), os.ModePerm))
+
+	updates, err := Updates(dir)
+	a.NotError(err).Length(updates, 5)
+
+	u := updates[0]
+	a.Equal(u.Module.Path, "example.com/a").
+		Equal(u.Patch, "v1.0.2").
+		Equal(u.Minor, "v1.1.0").
+		Equal(u.Major, []module.Version{
+			{Path: "example.com/a", Version: "v2.1.0+incompatible"},
+			{Path: "example.com/a/v3", Version: "v3.1.0"},
+		})
+
+	u = updates[1]
+	a.Empty(u.Patch).
+		Equal(u.Minor, "v1.5.0").
+		Equal(u.Major, []module.Version{
+			{Path: "example.com/Upper/v2", Version: "v2.0.0"},
+			{Path: "example.com/Upper/v3", Version: "v3.0.0"},
+		})
+
+	u = updates[2]
+	a.Empty(u.Patch).
+		Equal(u.Minor, "v2.4.0").
+		Equal(u.Major, []module.Version{{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"}})
+
+	u = updates[3]
+	a.Equal(u.Patch, "v2.0.1+incompatible").
+		Empty(u.Minor).
+		Equal(u.Major, []module.Version{
+			{Path: "example.com/inc/v2", Version: "v2.2.0"},
+			{Path: "example.com/inc/v3", Version: "v3.0.0"},
+		})
+
+	u = updates[4]
+	a.Empty(u.Patch).Empty(u.Minor).Empty(u.Major)
+}